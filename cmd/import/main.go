@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/joho/godotenv"
+	"github.com/maxik12233/task-junior/internal/config"
+	"github.com/maxik12233/task-junior/internal/importer"
+	"github.com/maxik12233/task-junior/internal/repository"
+	"github.com/maxik12233/task-junior/internal/service"
+	"github.com/maxik12233/task-junior/internal/transport"
+	"github.com/maxik12233/task-junior/pkg/logger"
+	"github.com/maxik12233/task-junior/pkg/name_info_sdk"
+	"github.com/maxik12233/task-junior/pkg/tenant"
+	"go.uber.org/zap"
+)
+
+func main() {
+	var (
+		filePath    = flag.String("file", "", "path to csv or ndjson file with persons")
+		formatFlag  = flag.String("format", "", "file format: csv or ndjson (default - by file extension)")
+		mappingFlag = flag.String("mapping", "", "column mapping in the column=field,column=field form")
+		reportPath  = flag.String("report", "import-errors.csv", "path to write the rejected rows report to")
+		tenantFlag  = flag.String("tenant", tenant.Default, "tenant to import persons into")
+	)
+	flag.Parse()
+
+	if *filePath == "" {
+		fmt.Fprintln(os.Stderr, "Fatal error: -file flag is required")
+		os.Exit(1)
+	}
+	if !tenant.Valid(*tenantFlag) {
+		fmt.Fprintln(os.Stderr, "Fatal error: -tenant flag must be up to 64 letters, digits, '_' and '-'")
+		os.Exit(1)
+	}
+
+	envErr := godotenv.Load(".env")
+
+	if err := config.BindConfig("config.yaml"); err != nil {
+		fmt.Fprintf(os.Stderr, "Fatal error couldn't bind config: %s \n", err)
+		os.Exit(1)
+	}
+	cfg := config.GetConfig()
+
+	log, closeLog, err := logger.Init(logger.Config{
+		Level:  cfg.Log.Level,
+		Format: cfg.Log.Format,
+		File:   logger.FileConfig(cfg.Log.File),
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Fatal error initializing logger: %s \n", err)
+		os.Exit(1)
+	}
+	defer closeLog()
+
+	if envErr != nil {
+		log.Info("Couldn't initialize env variables via .env file")
+	}
+
+	var format importer.Format
+	if *formatFlag != "" {
+		format, err = importer.ParseFormat(*formatFlag)
+	} else {
+		format, err = importer.FormatFromFilename(*filePath)
+	}
+	if err != nil {
+		log.Fatal("Fatal error bad format", zap.Error(err))
+	}
+
+	configMapping := importer.Mapping(cfg.ImportMapping)
+	if err := configMapping.Validate(); err != nil {
+		log.Fatal("Fatal error bad import mapping in config", zap.Error(err))
+	}
+	duplicates := service.DuplicateOptions{
+		Policy:    service.DuplicatePolicy(cfg.DuplicatePolicy),
+		Match:     cfg.DuplicateMatch,
+		Threshold: cfg.DuplicateThreshold,
+	}
+	if err := duplicates.Validate(); err != nil {
+		log.Fatal("Fatal error bad duplicate options in config", zap.Error(err))
+	}
+
+	mapping, err := importer.ParseMapping(*mappingFlag)
+	if err != nil {
+		log.Fatal("Fatal error bad mapping", zap.Error(err))
+	}
+
+	f, err := os.Open(*filePath)
+	if err != nil {
+		log.Fatal("Fatal error opening import file", zap.Error(err))
+	}
+	defer f.Close()
+
+	reader, err := importer.NewReader(f, format, configMapping.Merge(mapping), cfg.ImportMaxLineSize)
+	if err != nil {
+		log.Fatal("Fatal error reading import file", zap.Error(err))
+	}
+
+	dbSession, err := repository.EstablishPostgresConnection(cfg.PostgresConnectionString, repository.DBConfig{
+		MaxOpenConns:      cfg.Database.MaxOpenConns,
+		MaxIdleConns:      cfg.Database.MaxIdleConns,
+		ConnMaxLifetime:   cfg.Database.ConnMaxLifetime,
+		ConnMaxIdleTime:   cfg.Database.ConnMaxIdleTime,
+		StatementTimeout:  cfg.Database.StatementTimeout,
+		LogLevel:          cfg.Database.LogLevel,
+		SlowThreshold:     cfg.Database.SlowThreshold,
+		PrepareStatements: cfg.Database.PrepareStatements,
+		Replicas:          cfg.PostgresReplicaConnectionStrings,
+	}, log)
+	if err != nil {
+		log.Fatal(fmt.Sprintf("Fatal error database connection: %s \n", err))
+	}
+
+	repo := repository.NewRepository(dbSession, log, repository.Options{
+		Timeouts: repository.Timeouts(cfg.Database.QueryTimeouts),
+	})
+	// Files often repeat names, the cache saves the quota of the enrichment providers
+	nameInfo := name_info_sdk.NewNameInfo(cfg.Tenants[tenant.Normalize(*tenantFlag)].NameInfoAPIKey,
+		name_info_sdk.WithCache(cfg.EnrichmentCache.Size, cfg.EnrichmentCache.TTL))
+	svc := service.NewService(repo, log, nameInfo, service.Options{
+		Duplicates:       duplicates,
+		SearchSimilarity: cfg.SearchSimilarity,
+	})
+
+	validator, err := transport.NewValidator()
+	if err != nil {
+		log.Fatal("Fatal error creating validator", zap.Error(err))
+	}
+
+	// The report of the stopped import is written too, as the rows imported before the error stay stored
+	report, runErr := importer.Run(tenant.WithID(context.Background(), *tenantFlag), reader, transport.ImportRowHandler(svc, validator))
+	if runErr != nil {
+		log.Error("Error while importing persons, the import is stopped", zap.Error(runErr))
+	}
+
+	log.Info(fmt.Sprintf("Imported %d of %d rows, rejected %d", report.Imported, report.Total, len(report.Rejected)))
+	if len(report.Rejected) > 0 {
+		if err := writeReport(report, *reportPath); err != nil {
+			log.Fatal("Fatal error writing report file", zap.Error(err))
+		}
+		log.Info(fmt.Sprintf("Rejected rows report written to %s", *reportPath))
+	}
+
+	if runErr != nil {
+		// os.Exit skips the deferred calls
+		closeLog()
+		os.Exit(1)
+	}
+}
+
+func writeReport(report *importer.Report, path string) error {
+	reportFile, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer reportFile.Close()
+
+	return report.WriteCSV(reportFile)
+}
@@ -1,40 +1,120 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	app "github.com/maxik12233/task-junior"
 	"github.com/maxik12233/task-junior/internal/config"
+	"github.com/maxik12233/task-junior/internal/importer"
 	"github.com/maxik12233/task-junior/internal/repository"
 	"github.com/maxik12233/task-junior/internal/service"
 	"github.com/maxik12233/task-junior/internal/transport"
+	"github.com/maxik12233/task-junior/pkg/api"
+	"github.com/maxik12233/task-junior/pkg/api/auth"
 	"github.com/maxik12233/task-junior/pkg/api/logging"
 	"github.com/maxik12233/task-junior/pkg/api/paginate"
+	"github.com/maxik12233/task-junior/pkg/api/ratelimit"
 	"github.com/maxik12233/task-junior/pkg/api/sort"
 	"github.com/maxik12233/task-junior/pkg/cors"
+	"github.com/maxik12233/task-junior/pkg/health"
 	"github.com/maxik12233/task-junior/pkg/logger"
 	"github.com/maxik12233/task-junior/pkg/metrics"
 	"github.com/maxik12233/task-junior/pkg/name_info_sdk"
+	"github.com/maxik12233/task-junior/pkg/shutdown"
+	"github.com/maxik12233/task-junior/pkg/tenant"
+	"github.com/maxik12233/task-junior/pkg/tracing"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.uber.org/zap"
+)
+
+const (
+	serviceName = "task-junior"
+
+	idempotencyCleanupInterval = time.Hour
 )
 
 func main() {
-	log := logger.Init()
+	envErr := godotenv.Load(".env")
 
-	if err := godotenv.Load(".env"); err != nil {
+	// The logger is configured by the config, so errors before it are printed to stderr
+	if err := config.BindConfig("config.yaml"); err != nil {
+		fmt.Fprintf(os.Stderr, "Fatal error couldn't bind config: %s \n", err)
+		os.Exit(1)
+	}
+	cfg := config.GetConfig()
+
+	log, closeLog, err := logger.Init(logger.Config{
+		Level:  cfg.Log.Level,
+		Format: cfg.Log.Format,
+		File:   logger.FileConfig(cfg.Log.File),
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Fatal error initializing logger: %s \n", err)
+		os.Exit(1)
+	}
+	defer closeLog()
+
+	if envErr != nil {
 		log.Info("Couldn't initialize env variables via .env file")
 	}
 
-	if err := config.BindConfig("config.yaml"); err != nil {
-		log.Fatal(fmt.Sprintf("Fatal error couldn't bind config: %s \n", err))
+	importMapping := importer.Mapping(cfg.ImportMapping)
+	if err := importMapping.Validate(); err != nil {
+		log.Fatal(fmt.Sprintf("Fatal error bad import mapping: %s \n", err))
+	}
+
+	duplicates := service.DuplicateOptions{
+		Policy:    service.DuplicatePolicy(cfg.DuplicatePolicy),
+		Match:     cfg.DuplicateMatch,
+		Threshold: cfg.DuplicateThreshold,
+	}
+	if err := duplicates.Validate(); err != nil {
+		log.Fatal(fmt.Sprintf("Fatal error bad duplicate options: %s \n", err))
 	}
-	cfg := config.GetConfig()
 
-	dbSession, err := repository.EstablishPostgresConnection(cfg.PostgresConnectionString)
+	shutdownTracing, err := tracing.Init(context.Background(), tracing.Config{
+		ServiceName: serviceName,
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		log.Fatal(fmt.Sprintf("Fatal error initializing tracing: %s \n", err))
+	}
+
+	dbSession, err := repository.EstablishPostgresConnection(cfg.PostgresConnectionString, repository.DBConfig{
+		MaxOpenConns:      cfg.Database.MaxOpenConns,
+		MaxIdleConns:      cfg.Database.MaxIdleConns,
+		ConnMaxLifetime:   cfg.Database.ConnMaxLifetime,
+		ConnMaxIdleTime:   cfg.Database.ConnMaxIdleTime,
+		StatementTimeout:  cfg.Database.StatementTimeout,
+		LogLevel:          cfg.Database.LogLevel,
+		SlowThreshold:     cfg.Database.SlowThreshold,
+		PrepareStatements: cfg.Database.PrepareStatements,
+		Replicas:          cfg.PostgresReplicaConnectionStrings,
+	}, log)
 	if err != nil {
 		log.Fatal(fmt.Sprintf("Fatal error database connection: %s \n", err))
 	}
 
+	metric := metrics.New(log)
+	if err := repository.RegisterQueryObserver(dbSession, metric.ObserveDBQuery); err != nil {
+		log.Fatal(fmt.Sprintf("Fatal error registering query metrics: %s \n", err))
+	}
+	if err := repository.RegisterTracing(dbSession); err != nil {
+		log.Fatal(fmt.Sprintf("Fatal error registering query tracing: %s \n", err))
+	}
+
 	// Uncomment to switch to gorm automigration
 	/*if err := repository.DoAutoMigration(dbSession); err != nil {
 		log.Fatal(fmt.Sprintf("Fatal error auto migration: %s \n", err))
@@ -51,22 +131,229 @@ func main() {
 	} else {
 		router = gin.Default()
 	}
+	// gin trusts all proxies by default, so any client could spoof its IP
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatal(fmt.Sprintf("Fatal error bad trusted proxies: %s \n", err))
+	}
+	router.NoRoute(func(c *gin.Context) {
+		api.AbortWithError(c, app.ErrNotFound)
+	})
+	router.Use(otelgin.Middleware(serviceName))
+	router.Use(logging.RequestID(log))
+	router.Use(metric.Middleware())
 	router.Use(cors.CORSMiddleware())
-	router.Use(logging.ResponseLogger(log), logging.RequestLogger(log))
+	router.Use(logging.AccessLogger(log, logging.Config{
+		SuccessSampleRate: cfg.AccessLog.SuccessSampleRate,
+		SlowThreshold:     cfg.AccessLog.SlowThreshold,
+		Headers:           cfg.AccessLog.Headers,
+		RedactQuery:       cfg.AccessLog.RedactQuery,
+		RedactHeaders:     cfg.AccessLog.RedactHeaders,
+	}))
 	router.Use(paginate.Middleware(cfg.DefaultPage, cfg.DefaultPerPage))
-	router.Use(sort.Middleware(cfg.DefaultSortField, cfg.DefaultSortOrder))
+	router.Use(sort.Middleware(cfg.DefaultSortField, cfg.DefaultSortOrder, repository.SortFields...))
 
-	// Register general metrics endpoint
-	metric := metrics.Metric{Logger: log}
+	// Register general metrics endpoints
 	metric.Register(router)
 
+	// Register health probes
+	checks := []health.Check{
+		{
+			Name:    "postgres",
+			Timeout: cfg.Health.CheckTimeout,
+			Func: func(ctx context.Context) error {
+				return repository.Ping(ctx, dbSession)
+			},
+		},
+		{
+			Name:    "migrations",
+			Timeout: cfg.Health.CheckTimeout,
+			Func: func(ctx context.Context) error {
+				return repository.CheckMigrations(ctx, dbSession)
+			},
+		},
+	}
+
 	// Logic
-	repo := repository.NewRepository(dbSession, log)
-	svc := service.NewService(repo, log, name_info_sdk.NewNameInfo(""))
-	trans := transport.NewTransport(svc, log)
+	repo := repository.NewRepository(dbSession, log, repository.Options{
+		Timeouts: repository.Timeouts(cfg.Database.QueryTimeouts),
+	})
+	enrichmentOptions := []name_info_sdk.Option{
+		name_info_sdk.WithRequestObserver(metric.ObserveEnrichment),
+		name_info_sdk.WithCache(cfg.EnrichmentCache.Size, cfg.EnrichmentCache.TTL),
+		name_info_sdk.WithCacheObserver(func(provider string, hit bool) {
+			metric.ObserveCache("enrichment_"+provider, hit)
+		}),
+	}
+	tenantNameInfo := make(map[string]name_info_sdk.INameInfo)
+	tenantPerPage := make(map[string]int)
+	for id, v := range cfg.Tenants {
+		id = tenant.Normalize(id)
+		if v.NameInfoAPIKey != "" {
+			tenantNameInfo[id] = name_info_sdk.NewNameInfo(v.NameInfoAPIKey, enrichmentOptions...)
+		}
+		if v.DefaultPerPage != 0 {
+			tenantPerPage[id] = v.DefaultPerPage
+		}
+	}
+	nameInfo := name_info_sdk.NewNameInfo("", enrichmentOptions...)
+	svc := service.WithTracing(service.NewService(repo, log, nameInfo, service.Options{
+		Duplicates:       duplicates,
+		SearchSimilarity: cfg.SearchSimilarity,
+		TenantNameInfo:   tenantNameInfo,
+		IdempotencyTTL:   cfg.IdempotencyTTL,
+		IdempotencyLease: cfg.IdempotencyLease,
+	}))
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	workersDone := make(chan struct{})
+	go func() {
+		defer close(workersDone)
+		ticker := time.NewTicker(idempotencyCleanupInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-workersCtx.Done():
+				return
+			case <-ticker.C:
+				svc.DeleteExpiredIdempotencyKeys(workersCtx)
+			}
+		}
+	}()
+	if cfg.Health.ProbeEnrichment {
+		for _, reqInfo := range []name_info_sdk.RequestInfo{name_info_sdk.Age, name_info_sdk.Gender, name_info_sdk.Nationality} {
+			reqInfo := reqInfo
+			checks = append(checks, health.Check{
+				Name:     "enrichment_" + reqInfo.Provider(),
+				Timeout:  cfg.Health.EnrichmentTimeout,
+				Optional: true,
+				Func: func(ctx context.Context) error {
+					return nameInfo.Probe(ctx, reqInfo)
+				},
+			})
+		}
+	}
+	checker := health.NewChecker(log, checks...)
+	checker.Register(router)
+
+	validator, err := transport.NewValidator()
+	if err != nil {
+		log.Fatal(fmt.Sprintf("Fatal error creating validator: %s \n", err))
+	}
+	var authenticator *auth.Authenticator
+	if cfg.AuthEnabled {
+		bootstrapCtx := tenant.WithID(context.Background(), tenant.Default)
+		if cfg.AdminAPIKey != "" {
+			if err := svc.EnsureAdminAPIKey(bootstrapCtx, cfg.AdminAPIKey); err != nil {
+				log.Fatal(fmt.Sprintf("Fatal error creating admin api key: %s \n", err))
+			}
+		}
+		// Without JWT keys only api keys are accepted, and they are created by admins
+		if cfg.JWTSecret == "" && cfg.JWTPublicKeyFile == "" && cfg.JWKSFile == "" {
+			hasAdmin, err := svc.HasAdminAPIKey(bootstrapCtx)
+			if err != nil {
+				log.Fatal(fmt.Sprintf("Fatal error checking admin api keys: %s \n", err))
+			}
+			if !hasAdmin {
+				log.Fatal("Fatal error no JWT keys are configured and there is no active admin api key, set ADMIN_API_KEY to create one")
+			}
+		}
+
+		authenticator, err = auth.New(auth.Config{
+			Secret:        cfg.JWTSecret,
+			PublicKeyFile: cfg.JWTPublicKeyFile,
+			JWKSFile:      cfg.JWKSFile,
+			Issuer:        cfg.JWTIssuer,
+			Audience:      cfg.JWTAudience,
+			Leeway:        cfg.JWTLeeway,
+			APIKeys:       svc,
+		})
+		if err != nil {
+			log.Fatal(fmt.Sprintf("Fatal error creating authenticator: %s \n", err))
+		}
+	} else {
+		log.Warn("Authentication is disabled, person api is open to anyone")
+	}
+	var rateLimiter *ratelimit.Limiter
+	if cfg.RateLimit.Enabled {
+		routes := make(map[string]ratelimit.Limit)
+		for route, v := range cfg.RateLimit.Routes {
+			routes[route] = ratelimit.Limit(v)
+		}
+		rateLimiter, err = ratelimit.New(nil, ratelimit.Config{
+			Default: ratelimit.Limit(cfg.RateLimit.Default),
+			Routes:  routes,
+			IP:      ratelimit.Limit(cfg.RateLimit.IP),
+		}, log)
+		if err != nil {
+			log.Fatal(fmt.Sprintf("Fatal error creating rate limiter: %s \n", err))
+		}
+	}
+	trans := transport.NewTransport(svc, log, validator, transport.Options{
+		ImportMapping:     importMapping,
+		ImportMaxLineSize: cfg.ImportMaxLineSize,
+		Authenticator:     authenticator,
+		TenantPerPage:     tenantPerPage,
+		RateLimiter:       rateLimiter,
+		LogLevel:          logger.Level(),
+	})
 	trans.RegisterRoutes(router)
 
-	port := fmt.Sprintf(":%d", cfg.Port)
-	log.Info(fmt.Sprintf("Running server on port %s...", port))
-	router.Run(port)
+	server := &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.Port),
+		Handler:           router,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+
+	signalCtx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Info(fmt.Sprintf("Running server on port %s...", server.Addr))
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+
+	serverFailed := false
+	select {
+	case err := <-serverErr:
+		log.Error("Error running server", zap.Error(err))
+		serverFailed = true
+	case <-signalCtx.Done():
+		log.Info("Shutting down server...")
+	}
+	// The second signal kills the process at once
+	stopSignals()
+
+	// Stop getting new requests, then let the ones in progress finish.
+	// The server which has failed to start has no requests to drain.
+	shutdownCfg := shutdown.Config{
+		Timeout: cfg.Server.ShutdownTimeout,
+		Steps: []shutdown.Step{
+			{Name: "workers", Func: func(ctx context.Context) error {
+				stopWorkers()
+				<-workersDone
+				return nil
+			}},
+			{Name: "tracing", Func: shutdownTracing},
+			{Name: "database connection", Func: func(ctx context.Context) error {
+				return repository.CloseConnection(dbSession)
+			}},
+		},
+	}
+	if !serverFailed {
+		shutdownCfg.Drain = checker.Drain
+		shutdownCfg.DrainDelay = cfg.Server.DrainDelay
+	}
+	shutdown.Run(log, server, shutdownCfg)
+
+	if serverFailed {
+		// os.Exit skips the deferred calls
+		closeLog()
+		os.Exit(1)
+	}
+	log.Info("Server is stopped")
 }
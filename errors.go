@@ -15,14 +15,34 @@ var (
 	ErrValidation            = errors.New("Invalid request body")
 	ErrInvalidParamType      = errors.New("Invalid param type")
 	ErrNotAllRequiredQueries = errors.New("Not all queries")
+	ErrDuplicate             = errors.New("Duplicate")
+	ErrNotAcceptable         = errors.New("Not acceptable")
+	ErrUnauthorized          = errors.New("Unauthorized")
+	ErrForbidden             = errors.New("Forbidden")
+	ErrTooManyRequests       = errors.New("Too many requests")
+	ErrUnprocessable         = errors.New("Unprocessable")
+	ErrTimeout               = errors.New("Timeout")
+	ErrCanceled              = errors.New("Canceled")
 )
 
+// StatusClientClosedRequest is the nginx status of the request canceled by the client.
+const StatusClientClosedRequest = 499
+
 var errorCodesMap = map[error]int{
 	ErrNotFound:              404,
 	ErrInternal:              500,
 	ErrBadRequest:            400,
 	ErrValidation:            3,
+	ErrInvalidParamType:      4,
 	ErrNotAllRequiredQueries: 5,
+	ErrDuplicate:             6,
+	ErrNotAcceptable:         7,
+	ErrUnauthorized:          8,
+	ErrForbidden:             9,
+	ErrTooManyRequests:       10,
+	ErrUnprocessable:         11,
+	ErrTimeout:               12,
+	ErrCanceled:              13,
 }
 
 var codesToErrorsMap = map[int]error{
@@ -30,21 +50,48 @@ var codesToErrorsMap = map[int]error{
 	500: ErrInternal,
 	400: ErrBadRequest,
 	3:   ErrValidation,
+	4:   ErrInvalidParamType,
 	5:   ErrNotAllRequiredQueries,
+	6:   ErrDuplicate,
+	7:   ErrNotAcceptable,
+	8:   ErrUnauthorized,
+	9:   ErrForbidden,
+	10:  ErrTooManyRequests,
+	11:  ErrUnprocessable,
+	12:  ErrTimeout,
+	13:  ErrCanceled,
 }
 
+// WrapE wraps the application error with the message. The message is not a
+// format string, so it may hold any text, like the errors of the parsers.
 func WrapE(err error, msg string) error {
-	return fmt.Errorf("%w. Info: "+msg, err)
+	return fmt.Errorf("%w. Info: %s", err, msg)
 }
 
 func GetHTTPCodeFromError(err error) int {
-	switch err {
-	case ErrInternal:
+	switch {
+	case errors.Is(err, ErrInternal):
 		return http.StatusInternalServerError
-	case ErrNotFound:
+	case errors.Is(err, ErrNotFound):
 		return http.StatusNotFound
-	case ErrBadRequest, ErrValidation, ErrInvalidParamType:
+	case errors.Is(err, ErrBadRequest), errors.Is(err, ErrValidation), errors.Is(err, ErrInvalidParamType):
 		return http.StatusBadRequest
+	case errors.Is(err, ErrDuplicate):
+		return http.StatusConflict
+	case errors.Is(err, ErrNotAcceptable):
+		return http.StatusNotAcceptable
+	case errors.Is(err, ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, ErrTooManyRequests):
+		return http.StatusTooManyRequests
+	case errors.Is(err, ErrUnprocessable):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, ErrTimeout):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, ErrCanceled):
+		return StatusClientClosedRequest
 	default:
 		return http.StatusBadRequest
 	}
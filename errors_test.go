@@ -0,0 +1,27 @@
+package app
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWrapE(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  string
+		want string
+	}{
+		{name: "message", msg: "bad tenant id", want: "Bad request. Info: bad tenant id"},
+		{name: "percent signs are kept", msg: `invalid token: claim "%s" is 100%`, want: `Bad request. Info: invalid token: claim "%s" is 100%`},
+	}
+
+	for _, tt := range tests {
+		err := WrapE(ErrBadRequest, tt.msg)
+		if err.Error() != tt.want {
+			t.Errorf("%s: WrapE() = %q, want %q", tt.name, err.Error(), tt.want)
+		}
+		if !errors.Is(err, ErrBadRequest) {
+			t.Errorf("%s: WrapE() doesn't wrap the error", tt.name)
+		}
+	}
+}
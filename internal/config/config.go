@@ -3,6 +3,7 @@ package config
 import (
 	"path/filepath"
 	"runtime"
+	"time"
 
 	"github.com/caarlos0/env"
 	"github.com/spf13/viper"
@@ -21,6 +22,155 @@ type Config struct {
 	DefaultPerPage           int    `mapstructure:"default_per_page"`
 	DefaultSortField         string `mapstructure:"default_sort_field"`
 	DefaultSortOrder         string `mapstructure:"default_sort_order"`
+
+	ImportMapping map[string]string `mapstructure:"import_mapping"`
+	// ImportMaxLineSize is the longest ndjson line of the import in bytes.
+	ImportMaxLineSize int `mapstructure:"import_max_line_size"`
+
+	DuplicatePolicy    string  `mapstructure:"duplicate_policy"`
+	DuplicateMatch     string  `mapstructure:"duplicate_match"`
+	DuplicateThreshold float64 `mapstructure:"duplicate_threshold"`
+
+	SearchSimilarity float64 `mapstructure:"search_similarity"`
+
+	AuthEnabled bool   `mapstructure:"auth_enabled"`
+	JWTSecret   string `env:"JWT_SECRET"`
+	// AdminAPIKey is stored as an admin key of the default tenant at startup.
+	AdminAPIKey      string `env:"ADMIN_API_KEY"`
+	JWTPublicKeyFile string `mapstructure:"jwt_public_key_file"`
+	JWKSFile         string `mapstructure:"jwks_file"`
+	JWTIssuer        string `mapstructure:"jwt_issuer"`
+	JWTAudience      string `mapstructure:"jwt_audience"`
+	// JWTLeeway tolerates the clock skew between the issuer and the service.
+	JWTLeeway time.Duration `mapstructure:"jwt_leeway"`
+
+	Tenants map[string]TenantConfig `mapstructure:"tenants"`
+
+	RateLimit RateLimitConfig `mapstructure:"rate_limit"`
+	// TrustedProxies are the IPs and CIDRs of the proxies whose X-Forwarded-For
+	// and X-Real-IP headers give the client IP. Empty trusts none.
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
+
+	IdempotencyTTL   time.Duration `mapstructure:"idempotency_ttl"`
+	IdempotencyLease time.Duration `mapstructure:"idempotency_lease"`
+
+	Health HealthConfig `mapstructure:"health"`
+
+	EnrichmentCache EnrichmentCacheConfig `mapstructure:"enrichment_cache"`
+
+	Tracing TracingConfig `mapstructure:"tracing"`
+
+	AccessLog AccessLogConfig `mapstructure:"access_log"`
+
+	Log LogConfig `mapstructure:"log"`
+
+	Server ServerConfig `mapstructure:"server"`
+
+	Database DatabaseConfig `mapstructure:"database"`
+	// PostgresReplicaConnectionStrings are the read replicas, separated by commas.
+	PostgresReplicaConnectionStrings []string `env:"DB_REPLICA_CONNECTION_STRINGS" envSeparator:","`
+}
+
+// TenantConfig overrides the settings for the tenant. Zero values keep the defaults.
+type TenantConfig struct {
+	DefaultPerPage int    `mapstructure:"default_per_page"`
+	NameInfoAPIKey string `mapstructure:"name_info_api_key"`
+}
+
+type RateLimitConfig struct {
+	Enabled bool          `mapstructure:"enabled"`
+	Default RateLimitRule `mapstructure:"default"`
+	// Routes are keyed by method and route, e.g. "POST /person".
+	Routes map[string]RateLimitRule `mapstructure:"routes"`
+	// IP limits the requests of the client IP before authentication.
+	IP RateLimitRule `mapstructure:"ip"`
+}
+
+// RateLimitRule allows Requests per Period with bursts up to Burst requests.
+type RateLimitRule struct {
+	Requests int           `mapstructure:"requests"`
+	Period   time.Duration `mapstructure:"period"`
+	Burst    int           `mapstructure:"burst"`
+}
+
+type HealthConfig struct {
+	CheckTimeout time.Duration `mapstructure:"check_timeout"`
+	// ProbeEnrichment adds optional checks of the enrichment providers to the readiness.
+	ProbeEnrichment   bool          `mapstructure:"probe_enrichment"`
+	EnrichmentTimeout time.Duration `mapstructure:"enrichment_timeout"`
+}
+
+// EnrichmentCacheConfig keeps up to Size answers of each enrichment provider for TTL. Zero disables the cache.
+type EnrichmentCacheConfig struct {
+	Size int           `mapstructure:"size"`
+	TTL  time.Duration `mapstructure:"ttl"`
+}
+
+type TracingConfig struct {
+	// Exporter is none, stdout or otlp.
+	Exporter    string  `mapstructure:"exporter"`
+	Endpoint    string  `mapstructure:"endpoint"`
+	Insecure    bool    `mapstructure:"insecure"`
+	SampleRatio float64 `mapstructure:"sample_ratio"`
+}
+
+type AccessLogConfig struct {
+	SuccessSampleRate float64       `mapstructure:"success_sample_rate"`
+	SlowThreshold     time.Duration `mapstructure:"slow_threshold"`
+	Headers           []string      `mapstructure:"headers"`
+	RedactQuery       []string      `mapstructure:"redact_query"`
+	RedactHeaders     []string      `mapstructure:"redact_headers"`
+}
+
+// ServerConfig sets the HTTP server timeouts. Zero timeouts are unlimited.
+type ServerConfig struct {
+	ReadTimeout       time.Duration `mapstructure:"read_timeout"`
+	ReadHeaderTimeout time.Duration `mapstructure:"read_header_timeout"`
+	WriteTimeout      time.Duration `mapstructure:"write_timeout"`
+	IdleTimeout       time.Duration `mapstructure:"idle_timeout"`
+	// DrainDelay is the time the service stays unready, but still serves requests,
+	// after the shutdown signal, so the load balancer stops sending new ones.
+	DrainDelay time.Duration `mapstructure:"drain_delay"`
+	// ShutdownTimeout is the time to finish the requests in progress.
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+}
+
+type DatabaseConfig struct {
+	MaxOpenConns     int           `mapstructure:"max_open_conns"`
+	MaxIdleConns     int           `mapstructure:"max_idle_conns"`
+	ConnMaxLifetime  time.Duration `mapstructure:"conn_max_lifetime"`
+	ConnMaxIdleTime  time.Duration `mapstructure:"conn_max_idle_time"`
+	StatementTimeout time.Duration `mapstructure:"statement_timeout"`
+	// LogLevel of the queries is silent, error, warn or info.
+	LogLevel          string        `mapstructure:"log_level"`
+	SlowThreshold     time.Duration `mapstructure:"slow_threshold"`
+	PrepareStatements bool          `mapstructure:"prepare_statements"`
+	// QueryTimeouts are the deadlines of the repository operations.
+	QueryTimeouts QueryTimeoutsConfig `mapstructure:"query_timeouts"`
+}
+
+type QueryTimeoutsConfig struct {
+	Default time.Duration `mapstructure:"default"`
+	Read    time.Duration `mapstructure:"read"`
+	Write   time.Duration `mapstructure:"write"`
+	Search  time.Duration `mapstructure:"search"`
+	Export  time.Duration `mapstructure:"export"`
+}
+
+type LogConfig struct {
+	Level  string        `mapstructure:"level"`
+	Format string        `mapstructure:"format"`
+	File   LogFileConfig `mapstructure:"file"`
+}
+
+type LogFileConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`
+	Path           string        `mapstructure:"path"`
+	MaxSizeMB      int           `mapstructure:"max_size_mb"`
+	MaxAgeDays     int           `mapstructure:"max_age_days"`
+	MaxBackups     int           `mapstructure:"max_backups"`
+	RotateInterval time.Duration `mapstructure:"rotate_interval"`
+	Compress       bool          `mapstructure:"compress"`
 }
 
 func getCurrentPath() string {
@@ -0,0 +1,24 @@
+package domain
+
+import "time"
+
+// APIKey identifies a service client. The key itself is never stored, only its hash.
+type APIKey struct {
+	ID         uint
+	Name       string
+	Prefix     string
+	Scopes     []string
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+	TenantID   string
+}
+
+// IsActive reports whether the key is neither revoked nor expired at the moment.
+func (k APIKey) IsActive(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+// IdempotencyKey holds the response to the request made with the key, so
+// retries of the request get the same response instead of repeating it.
+type IdempotencyKey struct {
+	Key string
+	// RequestHash identifies the request payload the key was first used with.
+	RequestHash string
+	// StatusCode is zero while the first request is in progress.
+	StatusCode  int
+	ContentType string
+	Response    []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
+
+// IsCompleted reports whether the response to the request is stored.
+func (k IdempotencyKey) IsCompleted() bool {
+	return k.StatusCode != 0
+}
@@ -1,12 +1,18 @@
 package domain
 
+import "strings"
+
 type Person struct {
-	ID               uint
-	Name             string
-	Surname          string
-	Patronymic       string
-	CharacteristicID int
-	Characteristic   Characteristic
+	ID         uint
+	Name       string
+	Surname    string
+	Patronymic string
+	// Normalized latin forms of the name which are used for enrichment and search
+	NameNormalized       string
+	SurnameNormalized    string
+	PatronymicNormalized string
+	CharacteristicID     int
+	Characteristic       Characteristic
 }
 
 type Characteristic struct {
@@ -15,3 +21,17 @@ type Characteristic struct {
 	Gender      string
 	Nationality string
 }
+
+// PersonSearchResult is a person found by the search with its relevance score
+// and the full name with matched words highlighted.
+type PersonSearchResult struct {
+	Person    Person
+	Score     float64
+	Highlight string
+}
+
+// NormalizedFullName is the lower-cased full name with collapsed whitespace,
+// used to detect duplicates.
+func (p Person) NormalizedFullName() string {
+	return strings.ToLower(strings.Join(strings.Fields(p.Name+" "+p.Surname+" "+p.Patronymic), " "))
+}
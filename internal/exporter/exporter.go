@@ -0,0 +1,201 @@
+package exporter
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	app "github.com/maxik12233/task-junior"
+	"github.com/maxik12233/task-junior/internal/domain"
+	"github.com/xuri/excelize/v2"
+)
+
+type Format string
+
+const (
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson"
+	FormatXLSX   Format = "xlsx"
+)
+
+var header = []string{"id", "name", "surname", "patronymic", "gender", "age", "nationality"}
+
+func ParseFormat(format string) (Format, error) {
+	switch Format(strings.ToLower(format)) {
+	case FormatCSV, "":
+		return FormatCSV, nil
+	case FormatNDJSON:
+		return FormatNDJSON, nil
+	case FormatXLSX:
+		return FormatXLSX, nil
+	default:
+		return "", app.WrapE(app.ErrBadRequest, fmt.Sprintf("unsupported export format %q", format))
+	}
+}
+
+func (f Format) ContentType() string {
+	switch f {
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	default:
+		return "text/csv"
+	}
+}
+
+// Writer writes persons one by one. Close must be called to flush the output,
+// or Abort to release the writer when the output is left unfinished.
+type Writer interface {
+	Write(person *domain.Person) error
+	Close() error
+	Abort()
+}
+
+func NewWriter(w io.Writer, format Format) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(header); err != nil {
+			return nil, err
+		}
+		return &csvWriter{writer: writer}, nil
+	case FormatNDJSON:
+		return &ndjsonWriter{encoder: json.NewEncoder(w)}, nil
+	case FormatXLSX:
+		return newXLSXWriter(w)
+	default:
+		return nil, app.WrapE(app.ErrBadRequest, fmt.Sprintf("unsupported export format %q", format))
+	}
+}
+
+type csvWriter struct {
+	writer *csv.Writer
+}
+
+func (w *csvWriter) Write(person *domain.Person) error {
+	return w.writer.Write([]string{
+		strconv.Itoa(int(person.ID)),
+		person.Name,
+		person.Surname,
+		person.Patronymic,
+		person.Characteristic.Gender,
+		strconv.Itoa(person.Characteristic.Age),
+		person.Characteristic.Nationality,
+	})
+}
+
+func (w *csvWriter) Close() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+func (w *csvWriter) Abort() {}
+
+type ndjsonRecord struct {
+	Id          uint   `json:"id"`
+	Name        string `json:"name"`
+	Surname     string `json:"surname"`
+	Patronymic  string `json:"patronymic,omitempty"`
+	Gender      string `json:"gender"`
+	Age         int    `json:"age"`
+	Nationality string `json:"nationality"`
+}
+
+type ndjsonWriter struct {
+	encoder *json.Encoder
+}
+
+func (w *ndjsonWriter) Write(person *domain.Person) error {
+	return w.encoder.Encode(ndjsonRecord{
+		Id:          person.ID,
+		Name:        person.Name,
+		Surname:     person.Surname,
+		Patronymic:  person.Patronymic,
+		Gender:      person.Characteristic.Gender,
+		Age:         person.Characteristic.Age,
+		Nationality: person.Characteristic.Nationality,
+	})
+}
+
+func (w *ndjsonWriter) Close() error {
+	return nil
+}
+
+func (w *ndjsonWriter) Abort() {}
+
+// xlsxWriter uses excelize stream writer, which keeps rows in a temporary
+// file instead of memory, and copies the workbook to the output on Close.
+type xlsxWriter struct {
+	out    io.Writer
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	row    int
+	closed bool
+}
+
+const xlsxSheet = "Sheet1"
+
+func newXLSXWriter(w io.Writer) (*xlsxWriter, error) {
+	file := excelize.NewFile()
+	stream, err := file.NewStreamWriter(xlsxSheet)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	cells := make([]interface{}, len(header))
+	for i, v := range header {
+		cells[i] = v
+	}
+	if err := stream.SetRow("A1", cells); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &xlsxWriter{
+		out:    w,
+		file:   file,
+		stream: stream,
+		row:    1,
+	}, nil
+}
+
+func (w *xlsxWriter) Write(person *domain.Person) error {
+	w.row++
+	cell, err := excelize.CoordinatesToCellName(1, w.row)
+	if err != nil {
+		return err
+	}
+
+	return w.stream.SetRow(cell, []interface{}{
+		person.ID,
+		person.Name,
+		person.Surname,
+		person.Patronymic,
+		person.Characteristic.Gender,
+		person.Characteristic.Age,
+		person.Characteristic.Nationality,
+	})
+}
+
+func (w *xlsxWriter) Close() error {
+	defer w.Abort()
+
+	if err := w.stream.Flush(); err != nil {
+		return err
+	}
+	_, err := w.file.WriteTo(w.out)
+	return err
+}
+
+// Abort deletes the temporary files of the workbook without writing it.
+func (w *xlsxWriter) Abort() {
+	if !w.closed {
+		w.closed = true
+		w.file.Close()
+	}
+}
@@ -0,0 +1,133 @@
+package importer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strconv"
+	"sync"
+)
+
+// RowHandler validates and stores a single row. Returned error rejects the row.
+type RowHandler func(ctx context.Context, row Row) error
+
+type RejectedRow struct {
+	Line   int    `json:"line"`
+	Reason string `json:"reason"`
+}
+
+type Report struct {
+	ID       string        `json:"id"`
+	Total    int           `json:"total"`
+	Imported int           `json:"imported"`
+	Rejected []RejectedRow `json:"rejected"`
+	// Tenant is the tenant the persons were imported into.
+	Tenant string `json:"-"`
+}
+
+// Run reads all rows from the reader and passes each of them to the handler.
+// Malformed and rejected rows don't stop the import, they are collected in the report.
+func Run(ctx context.Context, reader Reader, handle RowHandler) (*Report, error) {
+	report := &Report{
+		ID:       newReportID(),
+		Rejected: []RejectedRow{},
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		row, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var lineErr *LineError
+			if !errors.As(err, &lineErr) {
+				return report, err
+			}
+			report.Total++
+			report.reject(lineErr.Line, lineErr.Err)
+			continue
+		}
+
+		report.Total++
+		if err := handle(ctx, row); err != nil {
+			report.reject(row.Line, err)
+			continue
+		}
+		report.Imported++
+	}
+
+	return report, nil
+}
+
+func (r *Report) reject(line int, err error) {
+	r.Rejected = append(r.Rejected, RejectedRow{
+		Line:   line,
+		Reason: err.Error(),
+	})
+}
+
+// WriteCSV writes rejected rows as a csv error report.
+func (r *Report) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"line", "reason"}); err != nil {
+		return err
+	}
+	for _, v := range r.Rejected {
+		if err := writer.Write([]string{strconv.Itoa(v.Line), v.Reason}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func newReportID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Store keeps the latest import reports in memory so they can be downloaded later.
+type Store struct {
+	mu      sync.Mutex
+	limit   int
+	order   []string
+	reports map[string]*Report
+}
+
+func NewStore(limit int) *Store {
+	return &Store{
+		limit:   limit,
+		reports: make(map[string]*Report),
+	}
+}
+
+func (s *Store) Save(report *Report) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.reports[report.ID]; !ok {
+		s.order = append(s.order, report.ID)
+	}
+	s.reports[report.ID] = report
+
+	for len(s.order) > s.limit {
+		delete(s.reports, s.order[0])
+		s.order = s.order[1:]
+	}
+}
+
+func (s *Store) Get(id string) (*Report, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report, ok := s.reports[id]
+	return report, ok
+}
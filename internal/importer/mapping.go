@@ -0,0 +1,73 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+
+	app "github.com/maxik12233/task-junior"
+)
+
+// Mapping maps source column names to person fields. Columns which are
+// neither mapped nor named as a person field are ignored.
+type Mapping map[string]string
+
+// ParseMapping parses mapping in the "column=field,column=field" form.
+func ParseMapping(s string) (Mapping, error) {
+	mapping := Mapping{}
+	if strings.TrimSpace(s) == "" {
+		return mapping, nil
+	}
+
+	for _, pair := range strings.Split(s, ",") {
+		column, field, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, app.WrapE(app.ErrBadRequest, fmt.Sprintf("bad mapping pair %q", pair))
+		}
+		mapping[column] = field
+	}
+
+	if err := mapping.Validate(); err != nil {
+		return nil, err
+	}
+
+	return mapping, nil
+}
+
+func (m Mapping) Validate() error {
+	for column, field := range m {
+		if !knownFields[normalize(field)] {
+			return app.WrapE(app.ErrBadRequest, fmt.Sprintf("column %q is mapped to unknown field %q", column, field))
+		}
+	}
+	return nil
+}
+
+// Merge returns a new mapping with the other mapping's entries taking precedence.
+func (m Mapping) Merge(other Mapping) Mapping {
+	merged := make(Mapping, len(m)+len(other))
+	for k, v := range m {
+		merged[normalize(k)] = v
+	}
+	for k, v := range other {
+		merged[normalize(k)] = v
+	}
+	return merged
+}
+
+// Field returns the person field for the given column or an empty string.
+func (m Mapping) Field(column string) string {
+	column = normalize(column)
+	for k, v := range m {
+		if normalize(k) == column {
+			return normalize(v)
+		}
+	}
+	if knownFields[column] {
+		return column
+	}
+	return ""
+}
+
+func normalize(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
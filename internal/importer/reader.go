@@ -0,0 +1,251 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	app "github.com/maxik12233/task-junior"
+)
+
+type Format string
+
+const (
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson"
+)
+
+// Person fields that a source column can be mapped to.
+const (
+	FieldName        = "name"
+	FieldSurname     = "surname"
+	FieldPatronymic  = "patronymic"
+	FieldGender      = "gender"
+	FieldAge         = "age"
+	FieldNationality = "nationality"
+)
+
+var knownFields = map[string]bool{
+	FieldName:        true,
+	FieldSurname:     true,
+	FieldPatronymic:  true,
+	FieldGender:      true,
+	FieldAge:         true,
+	FieldNationality: true,
+}
+
+// Row is a single parsed record with its fields already mapped to person fields.
+type Row struct {
+	Line   int
+	Fields map[string]string
+}
+
+// LineError is returned by Reader.Next when a single record is malformed.
+// Reading can continue after it.
+type LineError struct {
+	Line int
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+type Reader interface {
+	// Next returns the next row, a *LineError for a malformed row or io.EOF.
+	Next() (Row, error)
+}
+
+func ParseFormat(format string) (Format, error) {
+	switch Format(strings.ToLower(format)) {
+	case FormatCSV:
+		return FormatCSV, nil
+	case FormatNDJSON, "jsonl":
+		return FormatNDJSON, nil
+	default:
+		return "", app.WrapE(app.ErrBadRequest, fmt.Sprintf("unsupported import format %q", format))
+	}
+}
+
+// FormatFromFilename guesses the format by the file extension.
+func FormatFromFilename(filename string) (Format, error) {
+	return ParseFormat(strings.TrimPrefix(filepath.Ext(filename), "."))
+}
+
+// DefaultMaxLineSize is the longest ndjson line, in bytes, if the reader is given none.
+const DefaultMaxLineSize = 1 << 20
+
+// NewReader reads the rows of the format. Ndjson lines longer than maxLineSize
+// bytes are rejected as malformed, zero maxLineSize is DefaultMaxLineSize.
+func NewReader(r io.Reader, format Format, mapping Mapping, maxLineSize int) (Reader, error) {
+	if maxLineSize <= 0 {
+		maxLineSize = DefaultMaxLineSize
+	}
+
+	switch format {
+	case FormatCSV:
+		return newCSVReader(r, mapping)
+	case FormatNDJSON:
+		return &ndjsonReader{reader: bufio.NewReader(r), mapping: mapping, maxLineSize: maxLineSize}, nil
+	default:
+		return nil, app.WrapE(app.ErrBadRequest, fmt.Sprintf("unsupported import format %q", format))
+	}
+}
+
+type csvReader struct {
+	reader  *csv.Reader
+	columns []string
+}
+
+func newCSVReader(r io.Reader, mapping Mapping) (*csvReader, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, app.WrapE(app.ErrBadRequest, "empty csv file")
+	}
+	if err != nil {
+		return nil, app.WrapE(app.ErrBadRequest, fmt.Sprintf("bad csv header: %s", err))
+	}
+
+	columns := make([]string, len(header))
+	for i, v := range header {
+		columns[i] = mapping.Field(v)
+	}
+
+	return &csvReader{
+		reader:  reader,
+		columns: columns,
+	}, nil
+}
+
+func (r *csvReader) Next() (Row, error) {
+	record, err := r.reader.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return Row{}, &LineError{Line: parseErr.StartLine, Err: parseErr.Err}
+		}
+		return Row{}, err
+	}
+
+	line, _ := r.reader.FieldPos(0)
+	if len(record) != len(r.columns) {
+		return Row{}, &LineError{
+			Line: line,
+			Err:  fmt.Errorf("expected %d columns, got %d", len(r.columns), len(record)),
+		}
+	}
+
+	row := Row{
+		Line:   line,
+		Fields: make(map[string]string),
+	}
+	for i, v := range record {
+		if r.columns[i] != "" {
+			row.Fields[r.columns[i]] = strings.TrimSpace(v)
+		}
+	}
+
+	return row, nil
+}
+
+type ndjsonReader struct {
+	reader      *bufio.Reader
+	mapping     Mapping
+	maxLineSize int
+	line        int
+}
+
+func (r *ndjsonReader) Next() (Row, error) {
+	for {
+		text, err := r.readLine()
+		if err == io.EOF {
+			return Row{}, io.EOF
+		}
+		r.line++
+		if err != nil {
+			var lineErr *LineError
+			if errors.As(err, &lineErr) {
+				lineErr.Line = r.line
+			}
+			return Row{}, err
+		}
+
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+
+		decoder := json.NewDecoder(strings.NewReader(text))
+		decoder.UseNumber()
+
+		var object map[string]interface{}
+		if err := decoder.Decode(&object); err != nil {
+			return Row{}, &LineError{Line: r.line, Err: err}
+		}
+
+		row := Row{
+			Line:   r.line,
+			Fields: make(map[string]string),
+		}
+		for k, v := range object {
+			field := r.mapping.Field(k)
+			if field == "" || v == nil {
+				continue
+			}
+			row.Fields[field] = strings.TrimSpace(fmt.Sprint(v))
+		}
+
+		return row, nil
+	}
+}
+
+// readLine reads the next line without the line break. The line longer than
+// maxLineSize is skipped up to its end and returned as *LineError, so the rest
+// of the file can still be read.
+func (r *ndjsonReader) readLine() (string, error) {
+	var (
+		line    []byte
+		tooLong bool
+	)
+	for {
+		chunk, err := r.reader.ReadSlice('\n')
+		if !tooLong {
+			line = append(line, chunk...)
+			// Leaves room for the \r\n line break
+			if len(line) > r.maxLineSize+2 {
+				tooLong = true
+				line = nil
+			}
+		}
+
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err == io.EOF && (len(line) > 0 || tooLong) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		break
+	}
+
+	text := strings.TrimRight(string(line), "\r\n")
+	if tooLong || len(text) > r.maxLineSize {
+		return "", &LineError{Err: fmt.Errorf("line is longer than %d bytes", r.maxLineSize)}
+	}
+	return text, nil
+}
@@ -0,0 +1,197 @@
+package importer
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// readAll reads the rows and the lines of the malformed records.
+func readAll(t *testing.T, reader Reader) ([]Row, []int) {
+	t.Helper()
+
+	var (
+		rows      []Row
+		badLines  []int
+		lineError *LineError
+	)
+	for {
+		row, err := reader.Next()
+		if err == io.EOF {
+			return rows, badLines
+		}
+		if errors.As(err, &lineError) {
+			badLines = append(badLines, lineError.Line)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		rows = append(rows, row)
+	}
+}
+
+func TestReader(t *testing.T) {
+	tests := []struct {
+		name         string
+		format       Format
+		mapping      Mapping
+		maxLineSize  int
+		input        string
+		wantRows     []Row
+		wantBadLines []int
+	}{
+		{
+			name:   "csv",
+			format: FormatCSV,
+			input:  "name,surname,age\nIvan, Ivanov ,30\nPetr,Petrov,\n",
+			wantRows: []Row{
+				{Line: 2, Fields: map[string]string{"name": "Ivan", "surname": "Ivanov", "age": "30"}},
+				{Line: 3, Fields: map[string]string{"name": "Petr", "surname": "Petrov", "age": ""}},
+			},
+		},
+		{
+			name:    "csv with mapping and unknown columns",
+			format:  FormatCSV,
+			mapping: Mapping{"First Name": "name"},
+			input:   "first name,Surname,comment\nIvan,Ivanov,vip\n",
+			wantRows: []Row{
+				{Line: 2, Fields: map[string]string{"name": "Ivan", "surname": "Ivanov"}},
+			},
+		},
+		{
+			name:   "csv with wrong column count",
+			format: FormatCSV,
+			input:  "name,surname\nIvan\nPetr,Petrov\n",
+			wantRows: []Row{
+				{Line: 3, Fields: map[string]string{"name": "Petr", "surname": "Petrov"}},
+			},
+			wantBadLines: []int{2},
+		},
+		{
+			name:   "csv with bad quotes",
+			format: FormatCSV,
+			input:  "name,surname\n\"Ivan,Ivanov\nPetr,Petrov\n",
+			// The open quote swallows the rest of the file
+			wantBadLines: []int{2},
+		},
+		{
+			name:   "ndjson",
+			format: FormatNDJSON,
+			input:  "{\"name\":\"Ivan\",\"age\":30,\"gender\":null}\n\n{\"name\":\" Petr \",\"surname\":\"Petrov\"}\n",
+			wantRows: []Row{
+				{Line: 1, Fields: map[string]string{"name": "Ivan", "age": "30"}},
+				{Line: 3, Fields: map[string]string{"name": "Petr", "surname": "Petrov"}},
+			},
+		},
+		{
+			name:    "ndjson with mapping and bad line",
+			format:  FormatNDJSON,
+			mapping: Mapping{"given": "Name"},
+			input:   "{\"given\":\"Ivan\",\"extra\":1}\n{bad json}\n{\"NAME\":\"Petr\"}\n",
+			wantRows: []Row{
+				{Line: 1, Fields: map[string]string{"name": "Ivan"}},
+				{Line: 3, Fields: map[string]string{"name": "Petr"}},
+			},
+			wantBadLines: []int{2},
+		},
+		{
+			name:        "ndjson with long lines",
+			format:      FormatNDJSON,
+			maxLineSize: 15,
+			// The second line is over the limit, the third one is at it with \r\n, the last one is over it without the line break
+			input: "{\"name\":\"Ivan\"}\n{\"name\":\"" + strings.Repeat("x", 5000) + "\"}\n{\"name\":\"Petr\"}\r\n{\"name\":\"Sidor\"}",
+			wantRows: []Row{
+				{Line: 1, Fields: map[string]string{"name": "Ivan"}},
+				{Line: 3, Fields: map[string]string{"name": "Petr"}},
+			},
+			wantBadLines: []int{2, 4},
+		},
+		{
+			name:   "ndjson line over the default buffer",
+			format: FormatNDJSON,
+			input:  "{\"name\":\"" + strings.Repeat("x", 100000) + "\"}\n",
+			wantRows: []Row{
+				{Line: 1, Fields: map[string]string{"name": strings.Repeat("x", 100000)}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, err := NewReader(strings.NewReader(tt.input), tt.format, tt.mapping, tt.maxLineSize)
+			if err != nil {
+				t.Fatalf("NewReader() error = %v", err)
+			}
+
+			rows, badLines := readAll(t, reader)
+			if !reflect.DeepEqual(rows, tt.wantRows) {
+				t.Errorf("rows = %+v, want %+v", rows, tt.wantRows)
+			}
+			if !reflect.DeepEqual(badLines, tt.wantBadLines) {
+				t.Errorf("bad lines = %v, want %v", badLines, tt.wantBadLines)
+			}
+		})
+	}
+}
+
+func TestNewReaderErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		input  string
+	}{
+		{name: "empty csv", format: FormatCSV, input: ""},
+		{name: "bad csv header", format: FormatCSV, input: "\"name,surname\n"},
+		{name: "unknown format", format: "xml", input: "<persons/>"},
+	}
+
+	for _, tt := range tests {
+		if _, err := NewReader(strings.NewReader(tt.input), tt.format, nil, 0); err == nil {
+			t.Errorf("%s: NewReader() error = nil", tt.name)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Format
+		wantErr bool
+	}{
+		{in: "csv", want: FormatCSV},
+		{in: "CSV", want: FormatCSV},
+		{in: "ndjson", want: FormatNDJSON},
+		{in: "jsonl", want: FormatNDJSON},
+		{in: "xlsx", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseFormat(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseFormat(%q) = %q, %v, want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseMapping(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Mapping
+		wantErr bool
+	}{
+		{in: "", want: Mapping{}},
+		{in: "first=name,last=surname", want: Mapping{"first": "name", "last": "surname"}},
+		{in: "first", wantErr: true},
+		{in: "first=email", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseMapping(tt.in)
+		if (err != nil) != tt.wantErr || (!tt.wantErr && !reflect.DeepEqual(got, tt.want)) {
+			t.Errorf("ParseMapping(%q) = %v, %v, want %v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	app "github.com/maxik12233/task-junior"
+	"github.com/maxik12233/task-junior/internal/domain"
+	"go.uber.org/zap"
+	"gorm.io/gorm/clause"
+	"gorm.io/plugin/dbresolver"
+)
+
+func (r *Repository) CreateAPIKey(ctx context.Context, key domain.APIKey, keyHash string) (*domain.APIKey, error) {
+	ctx, cancel := r.withTimeout(ctx, r.options.Timeouts.Write)
+	defer cancel()
+
+	model := APIKey{
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		KeyHash:   keyHash,
+		Scopes:    strings.Join(key.Scopes, " "),
+		ExpiresAt: key.ExpiresAt,
+	}
+	result := r.db.WithContext(ctx).Create(&model)
+	if result.Error != nil {
+		return nil, r.queryError(ctx, "Error creating api key", result.Error)
+	}
+
+	created := model.ToDomain()
+	return &created, nil
+}
+
+func (r *Repository) GetAPIKeys(ctx context.Context) ([]*domain.APIKey, error) {
+	ctx, cancel := r.withTimeout(ctx, r.options.Timeouts.Read)
+	defer cancel()
+
+	var keys []*APIKey
+	result := r.db.WithContext(ctx).Order("id").Find(&keys)
+	if result.Error != nil {
+		return nil, r.queryError(ctx, "Error getting api keys", result.Error)
+	}
+
+	domainKeys := make([]*domain.APIKey, len(keys))
+	for i, v := range keys {
+		domainKey := v.ToDomain()
+		domainKeys[i] = &domainKey
+	}
+
+	return domainKeys, nil
+}
+
+func (r *Repository) GetAPIKeyByHash(ctx context.Context, keyHash string) (*domain.APIKey, error) {
+	ctx, cancel := r.withTimeout(ctx, r.options.Timeouts.Read)
+	defer cancel()
+
+	var key APIKey
+	// The tenant is not known until the key is found. Revoked and rotated keys
+	// must stop working at once, so the key is read from the primary.
+	result := r.db.WithContext(withoutTenantScope(ctx)).Clauses(dbresolver.Write).Where("key_hash = ?", keyHash).Limit(1).Find(&key)
+	if result.Error != nil {
+		return nil, r.queryError(ctx, "Error getting api key by hash", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, app.ErrNotFound
+	}
+
+	domainKey := key.ToDomain()
+	return &domainKey, nil
+}
+
+// RotateAPIKey replaces the hash of the active key, so the previous key stops working at once.
+func (r *Repository) RotateAPIKey(ctx context.Context, id uint, prefix, keyHash string) (*domain.APIKey, error) {
+	ctx, cancel := r.withTimeout(ctx, r.options.Timeouts.Write)
+	defer cancel()
+
+	var key APIKey
+	result := r.db.WithContext(ctx).Model(&key).Clauses(clause.Returning{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Updates(map[string]interface{}{"prefix": prefix, "key_hash": keyHash})
+	if result.Error != nil {
+		return nil, r.queryError(ctx, "Error rotating api key", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		r.log(ctx).Error("Error not found active api key to rotate", zap.Uint("id", id))
+		return nil, app.ErrNotFound
+	}
+
+	domainKey := key.ToDomain()
+	return &domainKey, nil
+}
+
+func (r *Repository) RevokeAPIKey(ctx context.Context, id uint) error {
+	ctx, cancel := r.withTimeout(ctx, r.options.Timeouts.Write)
+	defer cancel()
+
+	result := r.db.WithContext(ctx).Model(&APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return r.queryError(ctx, "Error revoking api key", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		r.log(ctx).Error("Error not found active api key to revoke", zap.Uint("id", id))
+		return app.ErrNotFound
+	}
+
+	return nil
+}
+
+// HasActiveAPIKey reports whether an active key with any of the scopes exists in any tenant.
+func (r *Repository) HasActiveAPIKey(ctx context.Context, scopes []string) (bool, error) {
+	ctx, cancel := r.withTimeout(ctx, r.options.Timeouts.Read)
+	defer cancel()
+
+	var count int64
+	result := r.db.WithContext(withoutTenantScope(ctx)).Clauses(dbresolver.Write).Model(&APIKey{}).
+		Where("revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", time.Now()).
+		Where("string_to_array(scopes, ' ') && string_to_array(?, ' ')", strings.Join(scopes, " ")).
+		Count(&count)
+	if result.Error != nil {
+		return false, r.queryError(ctx, "Error checking active api keys", result.Error)
+	}
+
+	return count > 0, nil
+}
+
+func (r *Repository) TouchAPIKey(ctx context.Context, id uint, usedAt time.Time) error {
+	ctx, cancel := r.withTimeout(ctx, r.options.Timeouts.Write)
+	defer cancel()
+
+	result := r.db.WithContext(withoutTenantScope(ctx)).Model(&APIKey{}).Where("id = ?", id).UpdateColumn("last_used_at", usedAt)
+	if result.Error != nil {
+		return r.queryError(ctx, "Error updating api key last usage", result.Error)
+	}
+
+	return nil
+}
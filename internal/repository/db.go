@@ -1,27 +1,141 @@
 package repository
 
 import (
+	"context"
 	_ "database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	_ "github.com/mattes/migrate/source/file"
+	"go.uber.org/zap"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
 )
 
-func EstablishPostgresConnection(dbUrl string) (*gorm.DB, error) {
-	db, err := gorm.Open(postgres.Open(dbUrl), &gorm.Config{})
+const migrationsDir = "internal/repository/migrations"
+
+// DBConfig tunes the connection pool and the query logging. Zero values keep the defaults.
+type DBConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	// StatementTimeout makes postgres cancel the longer statements. Zero disables it.
+	StatementTimeout time.Duration
+	// LogLevel of the queries is silent, error, warn (default) or info, which logs all queries at debug.
+	LogLevel string
+	// SlowThreshold makes the slower queries logged as warnings. Zero means 200ms.
+	SlowThreshold time.Duration
+	// PrepareStatements caches the prepared statements of the queries for the connections.
+	PrepareStatements bool
+	// Replicas are the connection strings of the read replicas. Person reads, searches
+	// and exports, and other queries out of transactions are sent to a random replica.
+	Replicas []string
+}
+
+func EstablishPostgresConnection(dbUrl string, cfg DBConfig, logger *zap.Logger) (*gorm.DB, error) {
+	queryLogger, err := newQueryLogger(logger, cfg.LogLevel, cfg.SlowThreshold)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := gorm.Open(postgres.Open(withStatementTimeout(dbUrl, cfg.StatementTimeout)), &gorm.Config{
+		Logger:      queryLogger,
+		PrepareStmt: cfg.PrepareStatements,
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	if cfg.MaxIdleConns != 0 {
+		sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	if len(cfg.Replicas) > 0 {
+		replicas := make([]gorm.Dialector, len(cfg.Replicas))
+		for i, v := range cfg.Replicas {
+			replicas[i] = postgres.Open(withStatementTimeout(v, cfg.StatementTimeout))
+		}
+		resolver := dbresolver.Register(dbresolver.Config{
+			Replicas: replicas,
+			Policy:   dbresolver.RandomPolicy{},
+		}).
+			SetMaxOpenConns(cfg.MaxOpenConns).
+			SetConnMaxLifetime(cfg.ConnMaxLifetime).
+			SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+		if cfg.MaxIdleConns != 0 {
+			resolver.SetMaxIdleConns(cfg.MaxIdleConns)
+		}
+		if err := db.Use(resolver); err != nil {
+			return nil, fmt.Errorf("registering read replicas: %w", err)
+		}
+	}
+
+	if err := registerTenantScope(db); err != nil {
+		return nil, err
+	}
+
 	return db, nil
 }
 
+// withStatementTimeout sets the statement_timeout parameter of the connections,
+// either in the URL or in the key-value connection string.
+func withStatementTimeout(dbUrl string, timeout time.Duration) string {
+	if timeout <= 0 {
+		return dbUrl
+	}
+	value := strconv.FormatInt(timeout.Milliseconds(), 10)
+
+	if strings.HasPrefix(dbUrl, "postgres://") || strings.HasPrefix(dbUrl, "postgresql://") {
+		u, err := url.Parse(dbUrl)
+		if err != nil {
+			// Left for the driver to report
+			return dbUrl
+		}
+		query := u.Query()
+		query.Set("statement_timeout", value)
+		u.RawQuery = query.Encode()
+		return u.String()
+	}
+	return dbUrl + " statement_timeout=" + value
+}
+
+// CloseConnection closes the connection pools of the primary and the replicas.
+// Queries in progress are finished first.
+func CloseConnection(db *gorm.DB) error {
+	if resolver, ok := db.Config.Plugins[(&dbresolver.DBResolver{}).Name()].(*dbresolver.DBResolver); ok {
+		return resolver.Call(func(connPool gorm.ConnPool) error {
+			if closer, ok := connPool.(interface{ Close() error }); ok {
+				return closer.Close()
+			}
+			return nil
+		})
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
 func DoAutoMigration(db *gorm.DB) error {
-	err := db.AutoMigrate(Person{}, Characteristic{})
+	err := db.AutoMigrate(Person{}, Characteristic{}, PersonMerge{}, APIKey{}, IdempotencyKey{})
 	if err != nil {
 		return err
 	}
@@ -30,7 +144,7 @@ func DoAutoMigration(db *gorm.DB) error {
 }
 
 func DoCommonMigration(dbUrl string) error {
-	m, err := migrate.New("file://internal/repository/migrations",
+	m, err := migrate.New("file://"+migrationsDir,
 		dbUrl)
 	if err != nil {
 		return err
@@ -41,7 +155,7 @@ func DoCommonMigration(dbUrl string) error {
 }
 
 func Rollback(dbUrl string) error {
-	m, err := migrate.New("file://internal/repository/migrations",
+	m, err := migrate.New("file://"+migrationsDir,
 		dbUrl)
 	if err != nil {
 		return err
@@ -50,3 +164,62 @@ func Rollback(dbUrl string) error {
 	m.Down()
 	return nil
 }
+
+// Ping checks the database connection through the pool.
+func Ping(ctx context.Context, db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	return sqlDB.PingContext(ctx)
+}
+
+// CheckMigrations reports an error if the database is not migrated to the
+// latest version in the migrations directory, or the last migration has failed.
+func CheckMigrations(ctx context.Context, db *gorm.DB) error {
+	latest, err := latestMigrationVersion()
+	if err != nil {
+		return err
+	}
+
+	var (
+		version uint64
+		dirty   bool
+	)
+	if err := db.WithContext(ctx).Raw("SELECT version, dirty FROM schema_migrations LIMIT 1").Row().Scan(&version, &dirty); err != nil {
+		return fmt.Errorf("reading migration version: %w", err)
+	}
+	if dirty {
+		return fmt.Errorf("migration %d has failed", version)
+	}
+	if version < latest {
+		return fmt.Errorf("migration version is %d, expected %d", version, latest)
+	}
+
+	return nil
+}
+
+func latestMigrationVersion() (uint64, error) {
+	entries, err := os.ReadDir(migrationsDir)
+	if err != nil {
+		return 0, err
+	}
+
+	var latest uint64
+	for _, entry := range entries {
+		prefix, _, ok := strings.Cut(entry.Name(), "_")
+		if !ok {
+			continue
+		}
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			continue
+		}
+		if version > latest {
+			latest = version
+		}
+	}
+
+	return latest, nil
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	app "github.com/maxik12233/task-junior"
+	"github.com/maxik12233/task-junior/internal/domain"
+	"go.uber.org/zap"
+	"gorm.io/gorm/clause"
+	"gorm.io/plugin/dbresolver"
+)
+
+// ReserveIdempotencyKey stores the key of the subject for the request in progress. It reports
+// false if the key is already used, unless the stored one is expired, or is in progress
+// for longer than the lease, as its request has crashed, and is taken over.
+func (r *Repository) ReserveIdempotencyKey(ctx context.Context, subject, key, requestHash string, expiresAt time.Time, lease time.Duration) (bool, error) {
+	ctx, cancel := r.withTimeout(ctx, r.options.Timeouts.Write)
+	defer cancel()
+
+	now := time.Now()
+	model := IdempotencyKey{
+		Subject:     subject,
+		Key:         key,
+		RequestHash: requestHash,
+		CreatedAt:   now,
+		ExpiresAt:   expiresAt,
+	}
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "tenant_id"}, {Name: "subject"}, {Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"request_hash", "status_code", "content_type", "response", "created_at", "expires_at",
+		}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{
+				SQL:  "idempotency_keys.expires_at < ? OR (idempotency_keys.status_code = 0 AND idempotency_keys.created_at < ?)",
+				Vars: []interface{}{now, now.Add(-lease)},
+			},
+		}},
+	}).Create(&model)
+	if result.Error != nil {
+		return false, r.queryError(ctx, "Error reserving idempotency key", result.Error)
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+func (r *Repository) GetIdempotencyKey(ctx context.Context, subject, key string) (*domain.IdempotencyKey, error) {
+	ctx, cancel := r.withTimeout(ctx, r.options.Timeouts.Read)
+	defer cancel()
+
+	var model IdempotencyKey
+	// The key has just been reserved on the primary, which replicas may not have yet
+	result := r.db.WithContext(ctx).Clauses(dbresolver.Write).Where("subject = ? AND key = ?", subject, key).Limit(1).Find(&model)
+	if result.Error != nil {
+		return nil, r.queryError(ctx, "Error getting idempotency key", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, app.ErrNotFound
+	}
+
+	domainKey := model.ToDomain()
+	return &domainKey, nil
+}
+
+func (r *Repository) SaveIdempotentResponse(ctx context.Context, subject, key string, statusCode int, contentType string, response []byte) error {
+	ctx, cancel := r.withTimeout(ctx, r.options.Timeouts.Write)
+	defer cancel()
+
+	result := r.db.WithContext(ctx).Model(&IdempotencyKey{}).Where("subject = ? AND key = ?", subject, key).
+		Updates(map[string]interface{}{
+			"status_code":  statusCode,
+			"content_type": contentType,
+			"response":     response,
+		})
+	if result.Error != nil {
+		return r.queryError(ctx, "Error saving idempotent response", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		r.log(ctx).Error("Error not found idempotency key to save response", zap.String("key", key))
+		return app.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *Repository) DeleteIdempotencyKey(ctx context.Context, subject, key string) error {
+	ctx, cancel := r.withTimeout(ctx, r.options.Timeouts.Write)
+	defer cancel()
+
+	result := r.db.WithContext(ctx).Where("subject = ? AND key = ?", subject, key).Delete(&IdempotencyKey{})
+	if result.Error != nil {
+		return r.queryError(ctx, "Error deleting idempotency key", result.Error)
+	}
+
+	return nil
+}
+
+// DeleteExpiredIdempotencyKeys deletes the expired keys of all tenants.
+func (r *Repository) DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int64, error) {
+	ctx, cancel := r.withTimeout(ctx, r.options.Timeouts.Write)
+	defer cancel()
+
+	result := r.db.WithContext(withoutTenantScope(ctx)).Where("expires_at < ?", now).Delete(&IdempotencyKey{})
+	if result.Error != nil {
+		return 0, r.queryError(ctx, "Error deleting expired idempotency keys", result.Error)
+	}
+
+	return result.RowsAffected, nil
+}
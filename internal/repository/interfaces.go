@@ -8,3 +8,12 @@ type PaginateOptions interface {
 	GetPage() uint64
 	GetPerPage() uint64
 }
+
+type MatchOptions interface {
+	GetMethod() string
+	GetThreshold() float64
+}
+
+type FilterOptions interface {
+	GetNationalities() []string
+}
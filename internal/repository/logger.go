@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/maxik12233/task-junior/pkg/logger"
+	"go.uber.org/zap"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+const defaultSlowThreshold = 200 * time.Millisecond
+
+var gormLogLevels = map[string]gormlogger.LogLevel{
+	"silent": gormlogger.Silent,
+	"error":  gormlogger.Error,
+	"warn":   gormlogger.Warn,
+	"info":   gormlogger.Info,
+}
+
+// queryLogger writes the gorm logs through zap with the fields of the request logger.
+// Failed queries are errors, slow queries are warnings and the rest are debug entries.
+type queryLogger struct {
+	logger        *zap.Logger
+	level         gormlogger.LogLevel
+	slowThreshold time.Duration
+}
+
+func newQueryLogger(l *zap.Logger, level string, slowThreshold time.Duration) (*queryLogger, error) {
+	logLevel := gormlogger.Warn
+	if level != "" {
+		var ok bool
+		if logLevel, ok = gormLogLevels[level]; !ok {
+			return nil, fmt.Errorf("bad query log level %q", level)
+		}
+	}
+	if slowThreshold <= 0 {
+		slowThreshold = defaultSlowThreshold
+	}
+
+	return &queryLogger{
+		logger:        l,
+		level:         logLevel,
+		slowThreshold: slowThreshold,
+	}, nil
+}
+
+func (l *queryLogger) log(ctx context.Context) *zap.Logger {
+	return logger.FromContext(ctx, l.logger)
+}
+
+func (l *queryLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	copied := *l
+	copied.level = level
+	return &copied
+}
+
+func (l *queryLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Info {
+		l.log(ctx).Info(fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l *queryLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Warn {
+		l.log(ctx).Warn(fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l *queryLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Error {
+		l.log(ctx).Error(fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l *queryLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.level <= gormlogger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && !errors.Is(err, gormlogger.ErrRecordNotFound) && l.level >= gormlogger.Error:
+		sql, rows := fc()
+		l.log(ctx).Error("Query failed", zap.String("sql", sql), zap.Int64("rows", rows), zap.Duration("elapsed", elapsed), zap.Error(err))
+	case elapsed > l.slowThreshold && l.level >= gormlogger.Warn:
+		sql, rows := fc()
+		l.log(ctx).Warn("Slow query", zap.String("sql", sql), zap.Int64("rows", rows), zap.Duration("elapsed", elapsed), zap.Duration("threshold", l.slowThreshold))
+	case l.level >= gormlogger.Info:
+		sql, rows := fc()
+		l.log(ctx).Debug("Query", zap.String("sql", sql), zap.Int64("rows", rows), zap.Duration("elapsed", elapsed))
+	}
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// QueryObserver is called after every query with its operation, like "query"
+// or "create", its table, duration and error, if the query has failed.
+type QueryObserver func(operation, table string, duration time.Duration, err error)
+
+const queryStartKey = "metrics:start"
+
+// RegisterQueryObserver makes the observer see all queries made through the db.
+func RegisterQueryObserver(db *gorm.DB, observe QueryObserver) error {
+	callbacks := []error{
+		db.Callback().Create().Before("*").Register("metrics:start", startQuery),
+		db.Callback().Create().After("*").Register("metrics:observe", observeQuery("create", observe)),
+		db.Callback().Query().Before("*").Register("metrics:start", startQuery),
+		db.Callback().Query().After("*").Register("metrics:observe", observeQuery("query", observe)),
+		db.Callback().Update().Before("*").Register("metrics:start", startQuery),
+		db.Callback().Update().After("*").Register("metrics:observe", observeQuery("update", observe)),
+		db.Callback().Delete().Before("*").Register("metrics:start", startQuery),
+		db.Callback().Delete().After("*").Register("metrics:observe", observeQuery("delete", observe)),
+		db.Callback().Row().Before("*").Register("metrics:start", startQuery),
+		db.Callback().Row().After("*").Register("metrics:observe", observeQuery("row", observe)),
+		db.Callback().Raw().Before("*").Register("metrics:start", startQuery),
+		db.Callback().Raw().After("*").Register("metrics:observe", observeQuery("raw", observe)),
+	}
+	for _, err := range callbacks {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func startQuery(db *gorm.DB) {
+	db.InstanceSet(queryStartKey, time.Now())
+}
+
+func observeQuery(operation string, observe QueryObserver) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(queryStartKey)
+		if !ok {
+			return
+		}
+		start, _ := value.(time.Time)
+
+		table := db.Statement.Table
+		if table == "" {
+			table = "unknown"
+		}
+
+		err := db.Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = nil
+		}
+		observe(operation, table, time.Since(start), err)
+	}
+}
@@ -0,0 +1,6 @@
+-- Normalized names are kept, they are valid for the previous version too
+CREATE TABLE IF NOT EXISTS data_migrations (
+    Name VARCHAR(255) PRIMARY KEY,
+    Applied_At TIMESTAMP NOT NULL DEFAULT now()
+);
+INSERT INTO data_migrations (Name) VALUES ('normalized_names') ON CONFLICT DO NOTHING;
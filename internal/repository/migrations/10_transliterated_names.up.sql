@@ -0,0 +1,64 @@
+-- Normalizes the names of the persons created before the service did it, as
+-- 4_normalized_names could only lower-case them. The functions repeat
+-- service.NormalizePerson: NFC and whitespace collapsing, ICAO Doc 9303
+-- transliteration of translit.ICAO, removal of the combining diacritical marks
+-- and lower case. It needs PostgreSQL 13 for normalize(). The migration replaces
+-- the backfill which was done by the application.
+BEGIN;
+
+-- lower() of the C collation only changes ASCII letters
+CREATE OR REPLACE FUNCTION tj_lower(s TEXT) RETURNS TEXT AS $$
+    SELECT lower(translate(s,
+        'АБВГДЕЁЖЗИЙКЛМНОПРСТУФХЦЧШЩЪЫЬЭЮЯЄІЇҐЎ',
+        'абвгдеёжзийклмнопрстуфхцчшщъыьэюяєіїґў'))
+$$ LANGUAGE SQL IMMUTABLE;
+
+CREATE OR REPLACE FUNCTION tj_clean(s TEXT) RETURNS TEXT AS $$
+    SELECT normalize(regexp_replace(trim(coalesce(s, '')), '\s+', ' ', 'g'), NFC)
+$$ LANGUAGE SQL IMMUTABLE;
+
+-- Letters missing in the second argument of translate, like ь, are removed
+CREATE OR REPLACE FUNCTION tj_icao(s TEXT) RETURNS TEXT AS $$
+    SELECT regexp_replace(translate(
+        replace(replace(replace(replace(replace(replace(replace(replace(replace(replace(
+            tj_lower(s),
+            'щ', 'shch'), 'ж', 'zh'), 'х', 'kh'), 'ц', 'ts'), 'ч', 'ch'), 'ш', 'sh'),
+            'ъ', 'ie'), 'ю', 'iu'), 'я', 'ia'), 'є', 'ie'),
+        'абвгдеёзийклмнопрстуфыэіїґўь',
+        'abvgdeeziiklmnoprstufyeiigu'),
+        '[\u0300-\u036f]', '', 'g')
+$$ LANGUAGE SQL IMMUTABLE;
+
+-- Row-level security hides the rows from the table owner too, it is lifted
+-- for the update only, in the transaction of the migration
+ALTER TABLE people NO FORCE ROW LEVEL SECURITY;
+
+UPDATE people p SET
+    Name_Normalized = n.Name_Normalized,
+    Surname_Normalized = n.Surname_Normalized,
+    Patronymic_Normalized = n.Patronymic_Normalized,
+    Full_Name_Normalized = n.Full_Name_Normalized
+FROM (
+    SELECT ID,
+        tj_icao(tj_clean(Name)) AS Name_Normalized,
+        tj_icao(tj_clean(Surname)) AS Surname_Normalized,
+        tj_icao(tj_clean(Patronymic)) AS Patronymic_Normalized,
+        tj_lower(trim(concat_ws(' ', nullif(tj_clean(Name), ''), nullif(tj_clean(Surname), ''), nullif(tj_clean(Patronymic), '')))) AS Full_Name_Normalized
+    FROM people
+) n
+WHERE p.ID = n.ID AND (
+    p.Name_Normalized IS DISTINCT FROM n.Name_Normalized OR
+    p.Surname_Normalized IS DISTINCT FROM n.Surname_Normalized OR
+    p.Patronymic_Normalized IS DISTINCT FROM n.Patronymic_Normalized OR
+    p.Full_Name_Normalized IS DISTINCT FROM n.Full_Name_Normalized);
+
+ALTER TABLE people FORCE ROW LEVEL SECURITY;
+
+DROP FUNCTION tj_icao(TEXT);
+DROP FUNCTION tj_clean(TEXT);
+DROP FUNCTION tj_lower(TEXT);
+
+-- It recorded the backfill of the application, which is done above now
+DROP TABLE IF EXISTS data_migrations;
+
+COMMIT;
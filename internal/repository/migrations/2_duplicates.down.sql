@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS person_merges;
+
+DROP INDEX IF EXISTS people_normalized_name_trgm_idx;
+
+ALTER TABLE people DROP COLUMN IF EXISTS Normalized_Name;
@@ -0,0 +1,17 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE EXTENSION IF NOT EXISTS fuzzystrmatch;
+
+ALTER TABLE people ADD COLUMN IF NOT EXISTS Normalized_Name VARCHAR(767) NOT NULL DEFAULT '';
+
+UPDATE people SET Normalized_Name = lower(regexp_replace(trim(concat_ws(' ', Name, Surname, Patronymic)), '\s+', ' ', 'g'));
+
+CREATE INDEX IF NOT EXISTS people_normalized_name_trgm_idx ON people USING GIN (Normalized_Name gin_trgm_ops);
+
+CREATE TABLE IF NOT EXISTS person_merges (
+    ID SERIAL PRIMARY KEY,
+    Source_ID INTEGER NOT NULL,
+    Target_ID INTEGER NOT NULL,
+    Source_Snapshot JSONB NOT NULL,
+    Merged_At TIMESTAMP NOT NULL DEFAULT now(),
+    FOREIGN KEY (Target_ID) REFERENCES people(ID) ON DELETE CASCADE
+);
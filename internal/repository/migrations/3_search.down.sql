@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS people_search_vector_idx;
+
+ALTER TABLE people DROP COLUMN IF EXISTS Search_Vector;
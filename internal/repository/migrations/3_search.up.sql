@@ -0,0 +1,4 @@
+ALTER TABLE people ADD COLUMN IF NOT EXISTS Search_Vector TSVECTOR
+    GENERATED ALWAYS AS (to_tsvector('simple', coalesce(Name, '') || ' ' || coalesce(Surname, '') || ' ' || coalesce(Patronymic, ''))) STORED;
+
+CREATE INDEX IF NOT EXISTS people_search_vector_idx ON people USING GIN (Search_Vector);
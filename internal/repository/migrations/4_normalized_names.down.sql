@@ -0,0 +1,16 @@
+ALTER TABLE people DROP COLUMN IF EXISTS Search_Vector;
+
+DROP INDEX IF EXISTS people_latin_name_trgm_idx;
+
+ALTER TABLE people DROP COLUMN IF EXISTS Name_Normalized;
+ALTER TABLE people DROP COLUMN IF EXISTS Surname_Normalized;
+ALTER TABLE people DROP COLUMN IF EXISTS Patronymic_Normalized;
+
+ALTER INDEX IF EXISTS people_full_name_normalized_trgm_idx RENAME TO people_normalized_name_trgm_idx;
+
+ALTER TABLE people RENAME COLUMN Full_Name_Normalized TO Normalized_Name;
+
+ALTER TABLE people ADD COLUMN Search_Vector TSVECTOR
+    GENERATED ALWAYS AS (to_tsvector('simple', coalesce(Name, '') || ' ' || coalesce(Surname, '') || ' ' || coalesce(Patronymic, ''))) STORED;
+
+CREATE INDEX IF NOT EXISTS people_search_vector_idx ON people USING GIN (Search_Vector);
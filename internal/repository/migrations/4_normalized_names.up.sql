@@ -0,0 +1,25 @@
+ALTER TABLE people RENAME COLUMN Normalized_Name TO Full_Name_Normalized;
+
+ALTER INDEX IF EXISTS people_normalized_name_trgm_idx RENAME TO people_full_name_normalized_trgm_idx;
+
+ALTER TABLE people ADD COLUMN IF NOT EXISTS Name_Normalized VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE people ADD COLUMN IF NOT EXISTS Surname_Normalized VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE people ADD COLUMN IF NOT EXISTS Patronymic_Normalized VARCHAR(255) NOT NULL DEFAULT '';
+
+UPDATE people SET
+    Name_Normalized = lower(trim(Name)),
+    Surname_Normalized = lower(trim(Surname)),
+    Patronymic_Normalized = lower(trim(coalesce(Patronymic, '')));
+
+CREATE INDEX IF NOT EXISTS people_latin_name_trgm_idx ON people
+    USING GIN ((Name_Normalized || ' ' || Surname_Normalized || ' ' || Patronymic_Normalized) gin_trgm_ops);
+
+-- Search vector has to be recreated to include normalized forms
+ALTER TABLE people DROP COLUMN IF EXISTS Search_Vector;
+
+ALTER TABLE people ADD COLUMN Search_Vector TSVECTOR
+    GENERATED ALWAYS AS (to_tsvector('simple',
+        coalesce(Name, '') || ' ' || coalesce(Surname, '') || ' ' || coalesce(Patronymic, '') || ' ' ||
+        Name_Normalized || ' ' || Surname_Normalized || ' ' || Patronymic_Normalized)) STORED;
+
+CREATE INDEX IF NOT EXISTS people_search_vector_idx ON people USING GIN (Search_Vector);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+    ID SERIAL PRIMARY KEY,
+    Name VARCHAR(255) NOT NULL,
+    Prefix VARCHAR(16) NOT NULL,
+    Key_Hash VARCHAR(64) NOT NULL UNIQUE,
+    Scopes VARCHAR(255) NOT NULL DEFAULT '',
+    Expires_At TIMESTAMP,
+    Last_Used_At TIMESTAMP,
+    Revoked_At TIMESTAMP,
+    Created_At TIMESTAMP NOT NULL DEFAULT now()
+);
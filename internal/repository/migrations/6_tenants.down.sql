@@ -0,0 +1,15 @@
+DROP POLICY IF EXISTS people_tenant_isolation ON people;
+ALTER TABLE people NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE people DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS characteristics_tenant_isolation ON characteristics;
+ALTER TABLE characteristics NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE characteristics DISABLE ROW LEVEL SECURITY;
+
+DROP INDEX IF EXISTS api_keys_tenant_id_idx;
+DROP INDEX IF EXISTS people_tenant_id_idx;
+DROP INDEX IF EXISTS characteristics_tenant_id_idx;
+
+ALTER TABLE api_keys DROP COLUMN IF EXISTS Tenant_ID;
+ALTER TABLE people DROP COLUMN IF EXISTS Tenant_ID;
+ALTER TABLE characteristics DROP COLUMN IF EXISTS Tenant_ID;
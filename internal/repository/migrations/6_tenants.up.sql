@@ -0,0 +1,23 @@
+ALTER TABLE characteristics ADD COLUMN IF NOT EXISTS Tenant_ID VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE people ADD COLUMN IF NOT EXISTS Tenant_ID VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS Tenant_ID VARCHAR(64) NOT NULL DEFAULT 'default';
+
+CREATE INDEX IF NOT EXISTS characteristics_tenant_id_idx ON characteristics (Tenant_ID);
+CREATE INDEX IF NOT EXISTS people_tenant_id_idx ON people (Tenant_ID);
+CREATE INDEX IF NOT EXISTS api_keys_tenant_id_idx ON api_keys (Tenant_ID);
+
+-- Row-level security is a safety net for the tenant scoping done by the application,
+-- which sets app.tenant_id in the transaction of every repository operation.
+-- It is forced for the table owner too, so data migrations must set app.tenant_id
+-- or disable it. Superusers and BYPASSRLS roles are not affected.
+ALTER TABLE characteristics ENABLE ROW LEVEL SECURITY;
+ALTER TABLE characteristics FORCE ROW LEVEL SECURITY;
+CREATE POLICY characteristics_tenant_isolation ON characteristics
+    USING (Tenant_ID = current_setting('app.tenant_id', true))
+    WITH CHECK (Tenant_ID = current_setting('app.tenant_id', true));
+
+ALTER TABLE people ENABLE ROW LEVEL SECURITY;
+ALTER TABLE people FORCE ROW LEVEL SECURITY;
+CREATE POLICY people_tenant_isolation ON people
+    USING (Tenant_ID = current_setting('app.tenant_id', true))
+    WITH CHECK (Tenant_ID = current_setting('app.tenant_id', true));
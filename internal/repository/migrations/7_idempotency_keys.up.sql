@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    ID SERIAL PRIMARY KEY,
+    Tenant_ID VARCHAR(64) NOT NULL DEFAULT 'default',
+    Key VARCHAR(255) NOT NULL,
+    Request_Hash VARCHAR(64) NOT NULL,
+    Status_Code INT NOT NULL DEFAULT 0,
+    Content_Type VARCHAR(255) NOT NULL DEFAULT '',
+    Response BYTEA,
+    Created_At TIMESTAMP NOT NULL DEFAULT now(),
+    Expires_At TIMESTAMP NOT NULL,
+    CONSTRAINT idempotency_keys_tenant_id_key_key UNIQUE (Tenant_ID, Key)
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON idempotency_keys (Expires_At);
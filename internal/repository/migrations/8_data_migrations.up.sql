@@ -0,0 +1,5 @@
+-- Data migrations which are done by the application, as they need its code
+CREATE TABLE IF NOT EXISTS data_migrations (
+    Name VARCHAR(255) PRIMARY KEY,
+    Applied_At TIMESTAMP NOT NULL DEFAULT now()
+);
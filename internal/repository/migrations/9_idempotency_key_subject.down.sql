@@ -0,0 +1,7 @@
+-- Keys of different clients may collide within the tenant
+DELETE FROM idempotency_keys WHERE Subject <> '';
+
+ALTER TABLE idempotency_keys DROP CONSTRAINT IF EXISTS idempotency_keys_tenant_id_subject_key_key;
+ALTER TABLE idempotency_keys ADD CONSTRAINT idempotency_keys_tenant_id_key_key UNIQUE (Tenant_ID, Key);
+
+ALTER TABLE idempotency_keys DROP COLUMN IF EXISTS Subject;
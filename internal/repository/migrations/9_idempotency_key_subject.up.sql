@@ -0,0 +1,5 @@
+-- Idempotency keys are chosen by the clients, so they are unique per client, not per tenant
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS Subject TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE idempotency_keys DROP CONSTRAINT IF EXISTS idempotency_keys_tenant_id_key_key;
+ALTER TABLE idempotency_keys ADD CONSTRAINT idempotency_keys_tenant_id_subject_key_key UNIQUE (Tenant_ID, Subject, Key);
@@ -1,14 +1,24 @@
 package repository
 
-import "github.com/maxik12233/task-junior/internal/domain"
+import (
+	"strings"
+	"time"
+
+	"github.com/maxik12233/task-junior/internal/domain"
+)
 
 type Person struct {
-	ID               uint   `gorm:"primary key"`
-	Name             string `gorm:"not null"`
-	Surname          string `gorm:"not null"`
-	Patronymic       string
-	CharacteristicID int
-	Characteristic   Characteristic
+	ID                   uint   `gorm:"primary key"`
+	Name                 string `gorm:"not null"`
+	Surname              string `gorm:"not null"`
+	Patronymic           string
+	NameNormalized       string `gorm:"not null;default:''"`
+	SurnameNormalized    string `gorm:"not null;default:''"`
+	PatronymicNormalized string `gorm:"not null;default:''"`
+	FullNameNormalized   string `gorm:"not null;default:''"`
+	CharacteristicID     int
+	Characteristic       Characteristic
+	TenantID             string `gorm:"not null;default:'default'"`
 }
 
 type Characteristic struct {
@@ -16,16 +26,78 @@ type Characteristic struct {
 	Age         int    `gorm:"not null"`
 	Gender      string `gorm:"not null"`
 	Nationality string `gorm:"not null"`
+	TenantID    string `gorm:"not null;default:'default'"`
+}
+
+// PersonMerge keeps the snapshot of the person which was merged into the target.
+type PersonMerge struct {
+	ID             uint      `gorm:"primary key"`
+	SourceID       uint      `gorm:"not null"`
+	TargetID       uint      `gorm:"not null"`
+	SourceSnapshot string    `gorm:"type:jsonb;not null"`
+	MergedAt       time.Time `gorm:"not null;autoCreateTime"`
+}
+
+// APIKey stores the sha256 hash of the key and its space separated scopes.
+type APIKey struct {
+	ID         uint   `gorm:"primary key"`
+	Name       string `gorm:"not null"`
+	Prefix     string `gorm:"not null"`
+	KeyHash    string `gorm:"not null;unique"`
+	Scopes     string `gorm:"not null;default:''"`
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time `gorm:"not null;autoCreateTime"`
+	TenantID   string    `gorm:"not null;default:'default'"`
+}
+
+// IdempotencyKey is unique per tenant and subject. Zero StatusCode means that the request is in progress.
+type IdempotencyKey struct {
+	ID          uint   `gorm:"primary key"`
+	TenantID    string `gorm:"not null;default:'default';uniqueIndex:idempotency_keys_tenant_id_subject_key_key"`
+	Subject     string `gorm:"not null;default:'';uniqueIndex:idempotency_keys_tenant_id_subject_key_key"`
+	Key         string `gorm:"not null;uniqueIndex:idempotency_keys_tenant_id_subject_key_key"`
+	RequestHash string `gorm:"not null"`
+	StatusCode  int    `gorm:"not null;default:0"`
+	ContentType string `gorm:"not null;default:''"`
+	Response    []byte
+	CreatedAt   time.Time `gorm:"not null;autoCreateTime"`
+	ExpiresAt   time.Time `gorm:"not null;index:idempotency_keys_expires_at_idx"`
+}
+
+// personRow is a flat person with its characteristic, used for cursor reads.
+type personRow struct {
+	ID                   uint
+	Name                 string
+	Surname              string
+	Patronymic           string
+	NameNormalized       string
+	SurnameNormalized    string
+	PatronymicNormalized string
+	CharacteristicID     int
+	Age                  int
+	Gender               string
+	Nationality          string
+}
+
+type personSearchRow struct {
+	Person    personRow `gorm:"embedded"`
+	Score     float64
+	Highlight string
 }
 
 func (p *Person) ToDomain() domain.Person {
 	return domain.Person{
-		ID:               p.ID,
-		Name:             p.Name,
-		Surname:          p.Surname,
-		Patronymic:       p.Patronymic,
-		CharacteristicID: p.CharacteristicID,
-		Characteristic:   p.Characteristic.ToDomain(),
+		ID:                   p.ID,
+		Name:                 p.Name,
+		Surname:              p.Surname,
+		Patronymic:           p.Patronymic,
+		NameNormalized:       p.NameNormalized,
+		SurnameNormalized:    p.SurnameNormalized,
+		PatronymicNormalized: p.PatronymicNormalized,
+		CharacteristicID:     p.CharacteristicID,
+		Characteristic:       p.Characteristic.ToDomain(),
 	}
 }
 
@@ -38,6 +110,33 @@ func (c *Characteristic) ToDomain() domain.Characteristic {
 	}
 }
 
+func (p *personRow) ToDomain() domain.Person {
+	return domain.Person{
+		ID:                   p.ID,
+		Name:                 p.Name,
+		Surname:              p.Surname,
+		Patronymic:           p.Patronymic,
+		NameNormalized:       p.NameNormalized,
+		SurnameNormalized:    p.SurnameNormalized,
+		PatronymicNormalized: p.PatronymicNormalized,
+		CharacteristicID:     p.CharacteristicID,
+		Characteristic: domain.Characteristic{
+			ID:          uint(p.CharacteristicID),
+			Age:         p.Age,
+			Gender:      p.Gender,
+			Nationality: p.Nationality,
+		},
+	}
+}
+
+func (p *personSearchRow) ToDomain() domain.PersonSearchResult {
+	return domain.PersonSearchResult{
+		Person:    p.Person.ToDomain(),
+		Score:     p.Score,
+		Highlight: markHighlight(p.Highlight),
+	}
+}
+
 func (p *Person) FromDomain(person domain.Person) {
 	if person.ID != 0 {
 		p.ID = person.ID
@@ -45,6 +144,10 @@ func (p *Person) FromDomain(person domain.Person) {
 	p.Name = person.Name
 	p.Surname = person.Surname
 	p.Patronymic = person.Patronymic
+	p.NameNormalized = person.NameNormalized
+	p.SurnameNormalized = person.SurnameNormalized
+	p.PatronymicNormalized = person.PatronymicNormalized
+	p.FullNameNormalized = person.NormalizedFullName()
 }
 
 func (p *Characteristic) FromDomain(person domain.Characteristic) {
@@ -52,3 +155,29 @@ func (p *Characteristic) FromDomain(person domain.Characteristic) {
 	p.Gender = person.Gender
 	p.Nationality = person.Nationality
 }
+
+func (k *APIKey) ToDomain() domain.APIKey {
+	return domain.APIKey{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     strings.Fields(k.Scopes),
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+		RevokedAt:  k.RevokedAt,
+		CreatedAt:  k.CreatedAt,
+		TenantID:   k.TenantID,
+	}
+}
+
+func (k *IdempotencyKey) ToDomain() domain.IdempotencyKey {
+	return domain.IdempotencyKey{
+		Key:         k.Key,
+		RequestHash: k.RequestHash,
+		StatusCode:  k.StatusCode,
+		ContentType: k.ContentType,
+		Response:    k.Response,
+		CreatedAt:   k.CreatedAt,
+		ExpiresAt:   k.ExpiresAt,
+	}
+}
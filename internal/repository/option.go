@@ -14,6 +14,25 @@ type paginateOptions struct {
 	PerPage int
 }
 
+type filterOptions struct {
+	Nationalities []string
+}
+
+type matchOptions struct {
+	Method    string
+	Threshold float64
+}
+
+// SortFields are the person columns which may be sorted by.
+var SortFields = []string{"id", "name", "surname", "patronymic"}
+
+// Duplicate matching methods.
+const (
+	MatchExact       = "exact"
+	MatchLevenshtein = "levenshtein"
+	MatchTrigram     = "trigram"
+)
+
 func NewSortOptions(field, order string) SortOptions {
 	return &sortOptions{
 		Field: field,
@@ -39,3 +58,31 @@ func (options *paginateOptions) GetPage() uint64 {
 func (options *paginateOptions) GetPerPage() uint64 {
 	return uint64(options.PerPage)
 }
+
+// NewMatchOptions creates duplicate matching options. Threshold is the minimal
+// similarity for trigram matching and the maximal distance for levenshtein.
+func NewMatchOptions(method string, threshold float64) MatchOptions {
+	return &matchOptions{
+		Method:    method,
+		Threshold: threshold,
+	}
+}
+
+func (options *matchOptions) GetMethod() string {
+	return options.Method
+}
+
+func (options *matchOptions) GetThreshold() float64 {
+	return options.Threshold
+}
+
+// NewFilterOptions creates person filter options. Empty nationalities match any person.
+func NewFilterOptions(nationalities []string) FilterOptions {
+	return &filterOptions{
+		Nationalities: nationalities,
+	}
+}
+
+func (options *filterOptions) GetNationalities() []string {
+	return options.Nationalities
+}
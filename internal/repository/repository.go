@@ -2,46 +2,100 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
 
 	app "github.com/maxik12233/task-junior"
 	"github.com/maxik12233/task-junior/internal/domain"
+	"github.com/maxik12233/task-junior/pkg/logger"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
 type IRepository interface {
-	GetPersonCount(ctx context.Context) (int64, error)
-	GetPersonAll(ctx context.Context, sortOptions SortOptions, paginateOptions PaginateOptions) ([]*domain.Person, error)
+	GetPersonCount(ctx context.Context, filterOptions FilterOptions) (int64, error)
+	GetPersonAll(ctx context.Context, sortOptions SortOptions, paginateOptions PaginateOptions, filterOptions FilterOptions) ([]*domain.Person, error)
 	GetPersonById(ctx context.Context, id uint) (*domain.Person, error)
 	CreatePerson(ctx context.Context, person domain.Person, char domain.Characteristic) error
 	DeletePerson(ctx context.Context, id int) error
 	UpdatePerson(ctx context.Context, person domain.Person, char domain.Characteristic) error
+	IteratePersons(ctx context.Context, sortOptions SortOptions, filterOptions FilterOptions, fn func(person *domain.Person) error) error
+	CountPersonsByNationality(ctx context.Context, filterOptions FilterOptions) (map[string]int64, error)
+	FindDuplicates(ctx context.Context, person domain.Person, matchOptions MatchOptions) ([]*domain.Person, error)
+	MergePerson(ctx context.Context, sourceId, targetId uint) (*domain.Person, error)
+	SearchPersons(ctx context.Context, query, normalizedQuery string, similarity float64, paginateOptions PaginateOptions) ([]*domain.PersonSearchResult, int64, error)
+
+	CreateAPIKey(ctx context.Context, key domain.APIKey, keyHash string) (*domain.APIKey, error)
+	GetAPIKeys(ctx context.Context) ([]*domain.APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (*domain.APIKey, error)
+	RotateAPIKey(ctx context.Context, id uint, prefix, keyHash string) (*domain.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id uint) error
+	HasActiveAPIKey(ctx context.Context, scopes []string) (bool, error)
+	TouchAPIKey(ctx context.Context, id uint, usedAt time.Time) error
+
+	ReserveIdempotencyKey(ctx context.Context, subject, key, requestHash string, expiresAt time.Time, lease time.Duration) (bool, error)
+	GetIdempotencyKey(ctx context.Context, subject, key string) (*domain.IdempotencyKey, error)
+	SaveIdempotentResponse(ctx context.Context, subject, key string, statusCode int, contentType string, response []byte) error
+	DeleteIdempotencyKey(ctx context.Context, subject, key string) error
+	DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int64, error)
 }
 
 //
 
 type Repository struct {
-	db     *gorm.DB
-	logger *zap.Logger
+	db      *gorm.DB
+	logger  *zap.Logger
+	options Options
+}
+
+type Options struct {
+	Timeouts Timeouts
 }
 
-func NewRepository(db *gorm.DB, logger *zap.Logger) IRepository {
+func NewRepository(db *gorm.DB, logger *zap.Logger, options Options) IRepository {
 	return &Repository{
-		db:     db,
-		logger: logger,
+		db:      db,
+		logger:  logger,
+		options: options,
+	}
+}
+
+// log returns the request-scoped logger of the context, or the repository logger.
+func (r *Repository) log(ctx context.Context) *zap.Logger {
+	return logger.FromContext(ctx, r.logger)
+}
+
+// filterPersons limits the people query to persons matching the filter.
+func (r *Repository) filterPersons(filterOptions FilterOptions) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if filterOptions == nil || len(filterOptions.GetNationalities()) == 0 {
+			return db
+		}
+
+		characteristics := db.Session(&gorm.Session{NewDB: true}).Model(&Characteristic{}).Select("id").
+			Where("nationality IN ?", filterOptions.GetNationalities())
+		return db.Where("people.characteristic_id IN (?)", characteristics)
 	}
 }
 
-func (r *Repository) GetPersonAll(ctx context.Context, sortOptions SortOptions, paginateOptions PaginateOptions) ([]*domain.Person, error) {
+func (r *Repository) GetPersonAll(ctx context.Context, sortOptions SortOptions, paginateOptions PaginateOptions, filterOptions FilterOptions) ([]*domain.Person, error) {
+	ctx, cancel := r.withTimeout(ctx, r.options.Timeouts.Read)
+	defer cancel()
+
 	var persons []*Person
-	result := r.db.Preload(clause.Associations).Offset(int(paginateOptions.GetPage()) * int(paginateOptions.GetPerPage())).
-		Limit(int(paginateOptions.GetPerPage())).
-		Order(sortOptions.GetOrderBy()).
-		Find(&persons)
+	result := r.readInTenant(ctx, func(tx *gorm.DB) *gorm.DB {
+		return tx.Preload(clause.Associations).Scopes(r.filterPersons(filterOptions)).Offset(int(paginateOptions.GetPage()) * int(paginateOptions.GetPerPage())).
+			Limit(int(paginateOptions.GetPerPage())).
+			Order(sortOptions.GetOrderBy()).
+			Find(&persons)
+	})
 	if result.Error != nil {
-		r.logger.Error("Error getting all person infos", zap.Error(result.Error))
-		return nil, app.ErrInternal
+		return nil, r.queryError(ctx, "Error getting all person infos", result.Error)
 	}
 
 	var domainPersons = make([]*domain.Person, len(persons))
@@ -53,107 +107,120 @@ func (r *Repository) GetPersonAll(ctx context.Context, sortOptions SortOptions,
 	return domainPersons, nil
 }
 
-func (r *Repository) GetPersonCount(ctx context.Context) (int64, error) {
+func (r *Repository) GetPersonCount(ctx context.Context, filterOptions FilterOptions) (int64, error) {
+	ctx, cancel := r.withTimeout(ctx, r.options.Timeouts.Read)
+	defer cancel()
+
 	var count int64
-	result := r.db.Model(&Person{}).Count(&count)
+	result := r.readInTenant(ctx, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&Person{}).Scopes(r.filterPersons(filterOptions)).Count(&count)
+	})
 	if result.Error != nil {
-		r.logger.Error("Error counting all person infos", zap.Error(result.Error))
-		return 0, app.ErrInternal
+		return 0, r.queryError(ctx, "Error counting all person infos", result.Error)
 	}
 
 	return count, nil
 }
 
 func (r *Repository) GetPersonById(ctx context.Context, id uint) (*domain.Person, error) {
+	ctx, cancel := r.withTimeout(ctx, r.options.Timeouts.Read)
+	defer cancel()
+
 	var p *Person
-	result := r.db.Preload(clause.Associations).Find(&p, id)
+	result := r.readInTenant(ctx, func(tx *gorm.DB) *gorm.DB {
+		return tx.Preload(clause.Associations).Find(&p, id)
+	})
+	if result.Error != nil {
+		return nil, r.queryError(ctx, "Error getting person by id", result.Error)
+	}
 	if result.RowsAffected == 0 {
-		r.logger.Error("Error not found while getting person by id")
+		r.log(ctx).Error("Error not found while getting person by id")
 		return nil, app.ErrNotFound
 	}
-	if result.Error != nil {
-		r.logger.Error("Error getting person by id", zap.Error(result.Error))
-		return nil, app.ErrInternal
-	}
 
 	resultValue := p.ToDomain()
 	return &resultValue, nil
 }
 
 func (r *Repository) CreatePerson(ctx context.Context, person domain.Person, char domain.Characteristic) error {
+	ctx, cancel := r.withTimeout(ctx, r.options.Timeouts.Write)
+	defer cancel()
+
 	createPerson := Person{}
 	createChar := Characteristic{}
 	createPerson.FromDomain(person)
 	createChar.FromDomain(char)
 	createPerson.Characteristic = createChar
 
-	result := r.db.Create(&createPerson)
+	result := r.inTenant(ctx, func(tx *gorm.DB) *gorm.DB {
+		return tx.Create(&createPerson)
+	})
 	if result.Error != nil {
-		r.logger.Error("Error creating new person info", zap.Error(result.Error))
-		return app.ErrInternal
+		return r.queryError(ctx, "Error creating new person info", result.Error)
 	}
 
 	return nil
 }
 
 func (r *Repository) DeletePerson(ctx context.Context, id int) error {
+	ctx, cancel := r.withTimeout(ctx, r.options.Timeouts.Write)
+	defer cancel()
 
-	var person Person
-	result := r.db.Where("id = ?", id).Find(&person)
-	if result.RowsAffected == 0 {
-		r.logger.Error("Error not found while deleting person info")
-		return app.ErrNotFound
-	}
-	if result.Error != nil {
-		r.logger.Error("Error while getting person by id", zap.Error(result.Error))
-		return app.ErrInternal
-	}
+	return r.tenantTx(ctx, func(tx *gorm.DB) error {
+		var person Person
+		result := tx.Where("id = ?", id).Find(&person)
+		if result.Error != nil {
+			return r.queryError(ctx, "Error while getting person by id", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			r.log(ctx).Error("Error not found while deleting person info")
+			return app.ErrNotFound
+		}
 
-	result = r.db.Unscoped().Delete(&Person{}, id)
-	if result.Error != nil {
-		r.logger.Error("Error while deleting person info", zap.Error(result.Error))
-		return app.ErrInternal
-	}
+		result = tx.Unscoped().Delete(&Person{}, id)
+		if result.Error != nil {
+			return r.queryError(ctx, "Error while deleting person info", result.Error)
+		}
 
-	result = r.db.Unscoped().Delete(&Characteristic{}, person.CharacteristicID)
-	if result.Error != nil {
-		r.logger.Error("Error while deleting person charactaristic", zap.Error(result.Error))
-		return app.ErrInternal
-	}
+		result = tx.Unscoped().Delete(&Characteristic{}, person.CharacteristicID)
+		if result.Error != nil {
+			return r.queryError(ctx, "Error while deleting person charactaristic", result.Error)
+		}
 
-	return nil
+		return nil
+	})
 }
 
 func (r *Repository) UpdatePerson(ctx context.Context, person domain.Person, char domain.Characteristic) error {
-	err := r.db.Transaction(func(tx *gorm.DB) error {
+	ctx, cancel := r.withTimeout(ctx, r.options.Timeouts.Write)
+	defer cancel()
+
+	err := r.tenantTx(ctx, func(tx *gorm.DB) error {
 		updatePerson := Person{}
 		updateChar := Characteristic{}
 		updatePerson.FromDomain(person)
 		updateChar.FromDomain(char)
 
 		var p Person
-		result := r.db.Where("id = ?", updatePerson.ID).Find(&p)
+		result := tx.Where("id = ?", updatePerson.ID).Find(&p)
+		if result.Error != nil {
+			return r.queryError(ctx, "Error getting person by id", result.Error)
+		}
 		if result.RowsAffected == 0 {
-			r.logger.Error("Error not found while getting person by id")
+			r.log(ctx).Error("Error not found while getting person by id")
 			return app.ErrNotFound
 		}
-		if result.Error != nil {
-			r.logger.Error("Error getting person by id", zap.Error(result.Error))
-			return app.ErrInternal
-		}
 
 		updateChar.ID = uint(p.CharacteristicID)
 
-		result = r.db.Save(&updateChar)
+		result = tx.Save(&updateChar)
 		if result.Error != nil {
-			r.logger.Error("Error updating person's charactatistic", zap.Error(result.Error))
-			return app.ErrInternal
+			return r.queryError(ctx, "Error updating person's charactatistic", result.Error)
 		}
 
-		result = r.db.Omit("Characteristic").Omit("CharacteristicID").Save(&updatePerson)
+		result = tx.Omit("Characteristic").Omit("CharacteristicID").Save(&updatePerson)
 		if result.Error != nil {
-			r.logger.Error("Error updating person", zap.Error(result.Error))
-			return app.ErrInternal
+			return r.queryError(ctx, "Error updating person", result.Error)
 		}
 
 		return nil
@@ -164,3 +231,314 @@ func (r *Repository) UpdatePerson(ctx context.Context, person domain.Person, cha
 
 	return nil
 }
+
+// IteratePersons reads persons one by one from a database cursor and passes them to fn.
+// Iteration stops on the first error returned by fn.
+func (r *Repository) IteratePersons(ctx context.Context, sortOptions SortOptions, filterOptions FilterOptions, fn func(person *domain.Person) error) error {
+	ctx, cancel := r.withTimeout(ctx, r.options.Timeouts.Export)
+	defer cancel()
+
+	// The export is limited by its own timeout, which may be longer than the statement_timeout
+	statementTimeout := strconv.FormatInt(r.timeout(r.options.Timeouts.Export).Milliseconds(), 10)
+
+	return r.readTx(ctx, func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT set_config('statement_timeout', ?, true)", statementTimeout).Error; err != nil {
+			return r.queryError(ctx, "Error setting export statement timeout", err)
+		}
+
+		joined := tx.Table("people").
+			Select("people.id, people.name, people.surname, people.patronymic, "+
+				"people.name_normalized, people.surname_normalized, people.patronymic_normalized, people.characteristic_id, "+
+				"characteristics.age, characteristics.gender, characteristics.nationality").
+			Joins("LEFT JOIN characteristics ON characteristics.id = people.characteristic_id").
+			Scopes(r.filterPersons(filterOptions), tenantPeople(ctx))
+
+		query := tx.Table("(?) AS p", joined)
+		if sortOptions != nil {
+			query = query.Order(sortOptions.GetOrderBy())
+		}
+
+		rows, err := query.Rows()
+		if err != nil {
+			return r.queryError(ctx, "Error opening person cursor", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var row personRow
+			if err := tx.ScanRows(rows, &row); err != nil {
+				return r.queryError(ctx, "Error scanning person row", err)
+			}
+
+			person := row.ToDomain()
+			if err := fn(&person); err != nil {
+				return err
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return r.queryError(ctx, "Error iterating person cursor", err)
+		}
+
+		return nil
+	})
+}
+
+// CountPersonsByNationality counts persons matching the filter grouped by their nationality.
+func (r *Repository) CountPersonsByNationality(ctx context.Context, filterOptions FilterOptions) (map[string]int64, error) {
+	ctx, cancel := r.withTimeout(ctx, r.options.Timeouts.Read)
+	defer cancel()
+
+	var rows []struct {
+		Nationality string
+		Count       int64
+	}
+	result := r.readInTenant(ctx, func(tx *gorm.DB) *gorm.DB {
+		return tx.Table("people").
+			Select("characteristics.nationality, count(*) AS count").
+			Joins("JOIN characteristics ON characteristics.id = people.characteristic_id").
+			Scopes(r.filterPersons(filterOptions), tenantPeople(ctx)).
+			Group("characteristics.nationality").
+			Scan(&rows)
+	})
+	if result.Error != nil {
+		return nil, r.queryError(ctx, "Error counting persons by nationality", result.Error)
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, v := range rows {
+		counts[v.Nationality] = v.Count
+	}
+
+	return counts, nil
+}
+
+// maxDuplicates limits how many possible duplicates are returned.
+const maxDuplicates = 10
+
+func (r *Repository) FindDuplicates(ctx context.Context, person domain.Person, matchOptions MatchOptions) ([]*domain.Person, error) {
+	ctx, cancel := r.withTimeout(ctx, r.options.Timeouts.Read)
+	defer cancel()
+
+	normalized := person.NormalizedFullName()
+
+	var match func(query *gorm.DB) *gorm.DB
+	switch matchOptions.GetMethod() {
+	case MatchExact:
+		match = func(query *gorm.DB) *gorm.DB {
+			return query.Where("full_name_normalized = ?", normalized)
+		}
+	case MatchLevenshtein:
+		// levenshtein fails on strings over 255 characters, so long names are compared by their beginning
+		distance := "levenshtein_less_equal(left(full_name_normalized, 255), left(?, 255), ?)"
+		maxDistance := int(matchOptions.GetThreshold())
+		match = func(query *gorm.DB) *gorm.DB {
+			return query.Where(distance+" <= ?", normalized, maxDistance, maxDistance).
+				Clauses(clause.OrderBy{Expression: clause.Expr{SQL: distance, Vars: []interface{}{normalized, maxDistance}}})
+		}
+	case MatchTrigram:
+		// The % operator uses the trigram index with pg_trgm.similarity_threshold set by the transaction
+		match = func(query *gorm.DB) *gorm.DB {
+			return query.Where("full_name_normalized % ?", normalized).
+				Clauses(clause.OrderBy{Expression: clause.Expr{SQL: "similarity(full_name_normalized, ?) DESC", Vars: []interface{}{normalized}}})
+		}
+	default:
+		r.log(ctx).Error(fmt.Sprintf("Unknown duplicate match method %q", matchOptions.GetMethod()))
+		return nil, app.ErrInternal
+	}
+
+	var persons []*Person
+	result := r.inTenant(ctx, func(tx *gorm.DB) *gorm.DB {
+		if matchOptions.GetMethod() == MatchTrigram {
+			threshold := strconv.FormatFloat(matchOptions.GetThreshold(), 'f', -1, 64)
+			if result := tx.Exec("SELECT set_config('pg_trgm.similarity_threshold', ?, true)", threshold); result.Error != nil {
+				return result
+			}
+		}
+		return tx.Preload(clause.Associations).Limit(maxDuplicates).Scopes(match).Find(&persons)
+	})
+	if result.Error != nil {
+		return nil, r.queryError(ctx, "Error finding duplicate persons", result.Error)
+	}
+
+	var domainPersons = make([]*domain.Person, len(persons))
+	for i, v := range persons {
+		domainPerson := v.ToDomain()
+		domainPersons[i] = &domainPerson
+	}
+
+	return domainPersons, nil
+}
+
+// MergePerson folds the source person into the target one. Empty target fields are
+// taken from the source, the source snapshot is kept in person_merges and the
+// source is deleted.
+func (r *Repository) MergePerson(ctx context.Context, sourceId, targetId uint) (*domain.Person, error) {
+	ctx, cancel := r.withTimeout(ctx, r.options.Timeouts.Write)
+	defer cancel()
+
+	var target Person
+	err := r.tenantTx(ctx, func(tx *gorm.DB) error {
+		var source Person
+		result := tx.Preload(clause.Associations).Find(&source, sourceId)
+		if result.Error != nil {
+			return r.queryError(ctx, "Error getting merge source person", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			r.log(ctx).Error("Error not found merge source person")
+			return app.ErrNotFound
+		}
+
+		result = tx.Preload(clause.Associations).Find(&target, targetId)
+		if result.Error != nil {
+			return r.queryError(ctx, "Error getting merge target person", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			r.log(ctx).Error("Error not found merge target person")
+			return app.ErrNotFound
+		}
+
+		if target.Patronymic == "" {
+			target.Patronymic = source.Patronymic
+			target.PatronymicNormalized = source.PatronymicNormalized
+			target.FullNameNormalized = domain.Person{
+				Name:       target.Name,
+				Surname:    target.Surname,
+				Patronymic: target.Patronymic,
+			}.NormalizedFullName()
+		}
+		if target.Characteristic.Age == 0 {
+			target.Characteristic.Age = source.Characteristic.Age
+		}
+		if target.Characteristic.Gender == "" {
+			target.Characteristic.Gender = source.Characteristic.Gender
+		}
+		if target.Characteristic.Nationality == "" {
+			target.Characteristic.Nationality = source.Characteristic.Nationality
+		}
+
+		if result := tx.Save(&target.Characteristic); result.Error != nil {
+			return r.queryError(ctx, "Error updating merge target charactaristic", result.Error)
+		}
+		if result := tx.Omit("Characteristic").Save(&target); result.Error != nil {
+			return r.queryError(ctx, "Error updating merge target person", result.Error)
+		}
+
+		snapshot, err := json.Marshal(source.ToDomain())
+		if err != nil {
+			return r.queryError(ctx, "Error marshaling merge source snapshot", err)
+		}
+		merge := PersonMerge{
+			SourceID:       source.ID,
+			TargetID:       target.ID,
+			SourceSnapshot: string(snapshot),
+		}
+		if result := tx.Create(&merge); result.Error != nil {
+			return r.queryError(ctx, "Error saving person merge history", result.Error)
+		}
+
+		// Repoint data which referenced the source person
+		result = tx.Model(&PersonMerge{}).Where("target_id = ?", source.ID).Update("target_id", target.ID)
+		if result.Error != nil {
+			return r.queryError(ctx, "Error repointing person merge history", result.Error)
+		}
+
+		if result := tx.Delete(&Person{}, source.ID); result.Error != nil {
+			return r.queryError(ctx, "Error deleting merge source person", result.Error)
+		}
+		if result := tx.Delete(&Characteristic{}, source.CharacteristicID); result.Error != nil {
+			return r.queryError(ctx, "Error deleting merge source charactaristic", result.Error)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resultValue := target.ToDomain()
+	return &resultValue, nil
+}
+
+const (
+	searchTSQuery   = "(plainto_tsquery('simple', @query) || plainto_tsquery('simple', @normalized))"
+	searchFullName  = "concat_ws(' ', people.name, people.surname, people.patronymic)"
+	searchLatinName = "(people.name_normalized || ' ' || people.surname_normalized || ' ' || people.patronymic_normalized)"
+	// The <% operators use the trigram indexes with pg_trgm.word_similarity_threshold
+	// set by the transaction, while the function is only used for ranking
+	searchCondition = "(people.search_vector @@ " + searchTSQuery +
+		" OR lower(@query) <% people.full_name_normalized OR @normalized <% " + searchLatinName + ")"
+	searchSimilarity = "GREATEST(word_similarity(lower(@query), people.full_name_normalized), " +
+		"word_similarity(@normalized, " + searchLatinName + "))"
+	searchScore = "ts_rank(people.search_vector, " + searchTSQuery + ") + " + searchSimilarity
+	// Matched words are delimited by control characters, which are removed from the name,
+	// and markHighlight turns them into marks after the name is HTML escaped
+	searchHighlight = "ts_headline('simple', translate(" + searchFullName + ", chr(2) || chr(3), ''), " + searchTSQuery +
+		", 'StartSel=\"' || chr(2) || '\", StopSel=\"' || chr(3) || '\", HighlightAll=true')"
+	searchStartSel = "\x02"
+	searchStopSel  = "\x03"
+)
+
+var highlightMarks = strings.NewReplacer(searchStartSel, "<mark>", searchStopSel, "</mark>")
+
+// markHighlight escapes the headline of the name for HTML and wraps the matched
+// words in <mark>, so the marks are its only markup.
+func markHighlight(headline string) string {
+	return highlightMarks.Replace(html.EscapeString(headline))
+}
+
+// SearchPersons finds persons by full-text match or trigram word similarity of either the
+// original or the normalized full name, ordered by relevance. Returns the page of results
+// and the total count.
+func (r *Repository) SearchPersons(ctx context.Context, query, normalizedQuery string, similarity float64, paginateOptions PaginateOptions) ([]*domain.PersonSearchResult, int64, error) {
+	ctx, cancel := r.withTimeout(ctx, r.options.Timeouts.Search)
+	defer cancel()
+
+	args := map[string]interface{}{
+		"query":      query,
+		"normalized": normalizedQuery,
+	}
+	threshold := strconv.FormatFloat(similarity, 'f', -1, 64)
+	withThreshold := func(tx *gorm.DB) error {
+		return tx.Exec("SELECT set_config('pg_trgm.word_similarity_threshold', ?, true)", threshold).Error
+	}
+
+	var count int64
+	result := r.readInTenant(ctx, func(tx *gorm.DB) *gorm.DB {
+		if err := withThreshold(tx); err != nil {
+			return &gorm.DB{Error: err}
+		}
+		return tx.Model(&Person{}).Where(searchCondition, args).Count(&count)
+	})
+	if result.Error != nil {
+		return nil, 0, r.queryError(ctx, "Error counting searched persons", result.Error)
+	}
+
+	var rows []*personSearchRow
+	result = r.readInTenant(ctx, func(tx *gorm.DB) *gorm.DB {
+		if err := withThreshold(tx); err != nil {
+			return &gorm.DB{Error: err}
+		}
+		return tx.Model(&Person{}).
+			Select("people.id, people.name, people.surname, people.patronymic, "+
+				"people.name_normalized, people.surname_normalized, people.patronymic_normalized, people.characteristic_id, "+
+				"characteristics.age, characteristics.gender, characteristics.nationality, "+
+				searchScore+" AS score, "+searchHighlight+" AS highlight", args).
+			Joins("LEFT JOIN characteristics ON characteristics.id = people.characteristic_id").
+			Where(searchCondition, args).
+			Order("score DESC, people.id").
+			Offset(int(paginateOptions.GetPage()) * int(paginateOptions.GetPerPage())).
+			Limit(int(paginateOptions.GetPerPage())).
+			Scan(&rows)
+	})
+	if result.Error != nil {
+		return nil, 0, r.queryError(ctx, "Error searching persons", result.Error)
+	}
+
+	var results = make([]*domain.PersonSearchResult, len(rows))
+	for i, v := range rows {
+		searchResult := v.ToDomain()
+		results[i] = &searchResult
+	}
+
+	return results, count, nil
+}
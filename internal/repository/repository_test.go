@@ -0,0 +1,28 @@
+package repository
+
+import "testing"
+
+func TestMarkHighlight(t *testing.T) {
+	tests := []struct {
+		name     string
+		headline string
+		want     string
+	}{
+		{name: "matched words", headline: "\x02Ivan\x03 \x02Ivanov\x03 Ivanovich", want: "<mark>Ivan</mark> <mark>Ivanov</mark> Ivanovich"},
+		{name: "no match", headline: "Ivan Ivanov", want: "Ivan Ivanov"},
+		{name: "script", headline: "<script>alert(1)</script> \x02Ivan\x03", want: "&lt;script&gt;alert(1)&lt;/script&gt; <mark>Ivan</mark>"},
+		{name: "attribute", headline: "\"><img src=x onerror=alert(1)> \x02Ivan\x03", want: "&#34;&gt;&lt;img src=x onerror=alert(1)&gt; <mark>Ivan</mark>"},
+		{name: "marks in the name", headline: "<mark>Ivan</mark> \x02Ivanov\x03", want: "&lt;mark&gt;Ivan&lt;/mark&gt; <mark>Ivanov</mark>"},
+		{name: "matched markup", headline: "\x02<b>Ivan</b>\x03", want: "<mark>&lt;b&gt;Ivan&lt;/b&gt;</mark>"},
+		{name: "entity", headline: "Tom &amp; \x02Jerry\x03", want: "Tom &amp;amp; <mark>Jerry</mark>"},
+		{name: "apostrophe", headline: "\x02O'Brien\x03", want: "<mark>O&#39;Brien</mark>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := markHighlight(tt.headline); got != tt.want {
+				t.Errorf("markHighlight(%q) = %q, want %q", tt.headline, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/maxik12233/task-junior/pkg/tenant"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/plugin/dbresolver"
+)
+
+const (
+	// tenantField is the field of models which belong to a tenant.
+	tenantField = "TenantID"
+	// tenantSetting is read by the row-level security policies.
+	tenantSetting = "app.tenant_id"
+)
+
+type tenantScopeKey struct{}
+
+// withoutTenantScope disables tenant scoping of queries made with the context.
+// It is only meant for lookups which find out the tenant, like API key verification.
+func withoutTenantScope(ctx context.Context) context.Context {
+	return context.WithValue(ctx, tenantScopeKey{}, false)
+}
+
+func tenantScoped(ctx context.Context) bool {
+	scoped, ok := ctx.Value(tenantScopeKey{}).(bool)
+	return !ok || scoped
+}
+
+// registerTenantScope makes every query of models with the TenantID field
+// limited to the tenant of the statement context, and sets the field on create and update.
+func registerTenantScope(db *gorm.DB) error {
+	callbacks := []error{
+		db.Callback().Create().Before("gorm:create").Register("tenant:set", setTenant),
+		db.Callback().Update().Before("gorm:update").Register("tenant:set", setTenant),
+		db.Callback().Query().Before("gorm:query").Register("tenant:scope", scopeTenant),
+		db.Callback().Update().Before("gorm:update").Register("tenant:scope", scopeTenant),
+		db.Callback().Delete().Before("gorm:delete").Register("tenant:scope", scopeTenant),
+		db.Callback().Row().Before("gorm:row").Register("tenant:scope", scopeTenant),
+	}
+	for _, err := range callbacks {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func tenantSchemaField(db *gorm.DB) (string, bool) {
+	if db.Statement.Schema == nil || !tenantScoped(db.Statement.Context) {
+		return "", false
+	}
+
+	field := db.Statement.Schema.LookUpField(tenantField)
+	if field == nil {
+		return "", false
+	}
+	return field.DBName, true
+}
+
+func scopeTenant(db *gorm.DB) {
+	column, ok := tenantSchemaField(db)
+	if !ok {
+		return
+	}
+
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: column}, Value: tenant.ID(db.Statement.Context)},
+	}})
+}
+
+func setTenant(db *gorm.DB) {
+	if _, ok := tenantSchemaField(db); !ok {
+		return
+	}
+
+	field := db.Statement.Schema.LookUpField(tenantField)
+	id := tenant.ID(db.Statement.Context)
+
+	switch value := db.Statement.ReflectValue; value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			db.AddError(field.Set(db.Statement.Context, reflect.Indirect(value.Index(i)), id))
+		}
+	case reflect.Struct:
+		db.AddError(field.Set(db.Statement.Context, value, id))
+	}
+}
+
+// tenantPeople limits raw queries of the people table, which are not scoped by the callbacks.
+func tenantPeople(ctx context.Context) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("people.tenant_id = ?", tenant.ID(ctx))
+	}
+}
+
+// tenantTx runs fn in a transaction with the tenant set for the row-level security policies.
+// The error of fn is returned as is, while the failed begin or commit is mapped to the app error.
+func (r *Repository) tenantTx(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return r.runTenantTx(ctx, r.transaction, fn)
+}
+
+// readTx is tenantTx for operations which only read. It runs on a read replica, if there are any.
+func (r *Repository) readTx(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return r.runTenantTx(ctx, r.readTransaction, fn)
+}
+
+func (r *Repository) runTenantTx(ctx context.Context, transaction func(ctx context.Context, fn func(tx *gorm.DB) error) error, fn func(tx *gorm.DB) error) error {
+	var fnErr error
+	err := transaction(ctx, func(tx *gorm.DB) error {
+		fnErr = fn(tx)
+		return fnErr
+	})
+	if err != nil && fnErr == nil {
+		return r.queryError(ctx, "Error running tenant transaction", err)
+	}
+	return err
+}
+
+// transaction is tenantTx returning the errors of the transaction itself as they are.
+func (r *Repository) transaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return tenantTransaction(ctx, r.db.WithContext(ctx), fn)
+}
+
+// readTransaction is transaction on a read replica, if there are any. Transactions
+// stay on the connection they are begun on, so the replica is chosen before the begin.
+func (r *Repository) readTransaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return tenantTransaction(ctx, r.db.WithContext(ctx).Clauses(dbresolver.Read), fn)
+}
+
+func tenantTransaction(ctx context.Context, db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT set_config(?, ?, true)", tenantSetting, tenant.ID(ctx)).Error; err != nil {
+			return err
+		}
+
+		return fn(tx)
+	})
+}
+
+// inTenant runs the single query in the tenant transaction and returns its result.
+func (r *Repository) inTenant(ctx context.Context, query func(tx *gorm.DB) *gorm.DB) *gorm.DB {
+	return inTransaction(ctx, r.transaction, query)
+}
+
+// readInTenant is inTenant for queries which only read. It runs on a read replica, if there are any.
+func (r *Repository) readInTenant(ctx context.Context, query func(tx *gorm.DB) *gorm.DB) *gorm.DB {
+	return inTransaction(ctx, r.readTransaction, query)
+}
+
+func inTransaction(ctx context.Context, transaction func(ctx context.Context, fn func(tx *gorm.DB) error) error, query func(tx *gorm.DB) *gorm.DB) *gorm.DB {
+	var result *gorm.DB
+	err := transaction(ctx, func(tx *gorm.DB) error {
+		result = query(tx)
+		return result.Error
+	})
+
+	if result == nil {
+		return &gorm.DB{Error: err}
+	}
+	if result.Error == nil && err != nil {
+		// The commit has failed
+		result.Error = err
+	}
+	return result
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	app "github.com/maxik12233/task-junior"
+	"go.uber.org/zap"
+)
+
+// queryCanceledCode is the postgres error of the statement canceled by statement_timeout.
+const queryCanceledCode = "57014"
+
+// Timeouts are the query deadlines of the operations. Zero ones fall back to
+// Default, and zero Default leaves the queries with the deadline of the request.
+type Timeouts struct {
+	Default time.Duration
+	// Read is the timeout of the person, api key and idempotency key lookups.
+	Read time.Duration
+	// Write is the timeout of the creates, updates, deletes and merges.
+	Write time.Duration
+	// Search is the timeout of the full text search.
+	Search time.Duration
+	// Export is the timeout of the whole export, which streams all persons.
+	Export time.Duration
+}
+
+// timeout returns the timeout, or the default one if it is zero. Zero means no timeout.
+func (r *Repository) timeout(timeout time.Duration) time.Duration {
+	if timeout <= 0 {
+		timeout = r.options.Timeouts.Default
+	}
+	if timeout <= 0 {
+		return 0
+	}
+	return timeout
+}
+
+// withTimeout limits the operation to the timeout, or to the default one if it is zero.
+func (r *Repository) withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	timeout = r.timeout(timeout)
+	if timeout == 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// queryError logs the failed query and returns its app error. Queries stopped by
+// the deadline are app.ErrTimeout and queries of the canceled request app.ErrCanceled.
+func (r *Repository) queryError(ctx context.Context, msg string, err error) error {
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(ctx.Err(), context.DeadlineExceeded),
+		errors.As(err, &pgErr) && pgErr.Code == queryCanceledCode && ctx.Err() == nil:
+		r.log(ctx).Warn(msg+": query timed out", zap.Error(err))
+		return app.ErrTimeout
+	case errors.Is(err, context.Canceled), errors.Is(ctx.Err(), context.Canceled):
+		r.log(ctx).Warn(msg+": request is canceled", zap.Error(err))
+		return app.ErrCanceled
+	default:
+		r.log(ctx).Error(msg, zap.Error(err))
+		return app.ErrInternal
+	}
+}
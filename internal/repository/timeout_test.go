@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	app "github.com/maxik12233/task-junior"
+	"go.uber.org/zap"
+)
+
+func TestQueryError(t *testing.T) {
+	canceledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+	expiredCtx, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+
+	statementTimeout := &pgconn.PgError{Code: queryCanceledCode, Message: "canceling statement due to statement timeout"}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want error
+	}{
+		{name: "deadline", ctx: context.Background(), err: context.DeadlineExceeded, want: app.ErrTimeout},
+		{name: "wrapped deadline", ctx: context.Background(), err: fmt.Errorf("query: %w", context.DeadlineExceeded), want: app.ErrTimeout},
+		{name: "expired context", ctx: expiredCtx, err: errors.New("conn closed"), want: app.ErrTimeout},
+		{name: "statement timeout", ctx: context.Background(), err: statementTimeout, want: app.ErrTimeout},
+		{name: "statement canceled with the request", ctx: canceledCtx, err: statementTimeout, want: app.ErrCanceled},
+		{name: "canceled", ctx: context.Background(), err: context.Canceled, want: app.ErrCanceled},
+		{name: "canceled context", ctx: canceledCtx, err: errors.New("conn closed"), want: app.ErrCanceled},
+		{name: "other postgres error", ctx: context.Background(), err: &pgconn.PgError{Code: "23505"}, want: app.ErrInternal},
+		{name: "other error", ctx: context.Background(), err: errors.New("boom"), want: app.ErrInternal},
+	}
+
+	r := &Repository{logger: zap.NewNop()}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.queryError(tt.ctx, "Error", tt.err); got != tt.want {
+				t.Errorf("queryError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	tests := []struct {
+		name     string
+		defaults time.Duration
+		timeout  time.Duration
+		want     time.Duration
+	}{
+		{name: "own timeout", defaults: time.Second, timeout: time.Minute, want: time.Minute},
+		{name: "default timeout", defaults: time.Second, want: time.Second},
+		{name: "no timeout", want: 0},
+	}
+
+	for _, tt := range tests {
+		r := &Repository{options: Options{Timeouts: Timeouts{Default: tt.defaults}}}
+		if got := r.timeout(tt.timeout); got != tt.want {
+			t.Errorf("%s: timeout() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/maxik12233/task-junior/pkg/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const querySpanKey = "tracing:span"
+
+var tracer = otel.Tracer("github.com/maxik12233/task-junior/internal/repository")
+
+// RegisterTracing makes every query made through the db a span of the trace of its context.
+func RegisterTracing(db *gorm.DB) error {
+	callbacks := []error{
+		db.Callback().Create().Before("*").Register("tracing:start", startQuerySpan("create")),
+		db.Callback().Create().After("*").Register("tracing:end", endQuerySpan),
+		db.Callback().Query().Before("*").Register("tracing:start", startQuerySpan("query")),
+		db.Callback().Query().After("*").Register("tracing:end", endQuerySpan),
+		db.Callback().Update().Before("*").Register("tracing:start", startQuerySpan("update")),
+		db.Callback().Update().After("*").Register("tracing:end", endQuerySpan),
+		db.Callback().Delete().Before("*").Register("tracing:start", startQuerySpan("delete")),
+		db.Callback().Delete().After("*").Register("tracing:end", endQuerySpan),
+		db.Callback().Row().Before("*").Register("tracing:start", startQuerySpan("row")),
+		db.Callback().Row().After("*").Register("tracing:end", endQuerySpan),
+		db.Callback().Raw().Before("*").Register("tracing:start", startQuerySpan("raw")),
+		db.Callback().Raw().After("*").Register("tracing:end", endQuerySpan),
+	}
+	for _, err := range callbacks {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func startQuerySpan(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx, span := tracer.Start(db.Statement.Context, "gorm."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBSystemPostgreSQL,
+				semconv.DBOperation(operation),
+				semconv.DBSQLTable(db.Statement.Table),
+			),
+		)
+		db.Statement.Context = ctx
+		db.InstanceSet(querySpanKey, span)
+	}
+}
+
+func endQuerySpan(db *gorm.DB) {
+	value, ok := db.InstanceGet(querySpanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+
+	span.SetAttributes(
+		semconv.DBStatement(db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+	)
+	err := db.Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = nil
+	}
+	tracing.End(span, err)
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	app "github.com/maxik12233/task-junior"
+	"github.com/maxik12233/task-junior/internal/domain"
+	"github.com/maxik12233/task-junior/pkg/api/auth"
+	"go.uber.org/zap"
+)
+
+const (
+	apiKeyPrefix = "tj_"
+	// apiKeyShownPrefix is how many chars of the key are stored to recognize it in listings.
+	apiKeyShownPrefix = len(apiKeyPrefix) + 8
+	apiKeyBytes       = 32
+	// adminAPIKeyMinLength is the minimal length of the admin key set by the operator.
+	adminAPIKeyMinLength = 32
+	// apiKeyTouchInterval is how often the last usage of a key is recorded.
+	apiKeyTouchInterval = time.Minute
+)
+
+// adminScopes are the scopes which allow to manage api keys.
+var adminScopes = []string{string(auth.RoleAdmin), string(auth.PermissionAPIKeyManage)}
+
+// generateAPIKey returns the new key with its stored prefix and hash.
+func generateAPIKey() (key, prefix, hash string, err error) {
+	b := make([]byte, apiKeyBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", "", err
+	}
+
+	key = apiKeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return key, key[:apiKeyShownPrefix], hashAPIKey(key), nil
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateAPIKey stores the new key and returns it in plain text. It can't be recovered later.
+func (s *Service) CreateAPIKey(ctx context.Context, key domain.APIKey) (*domain.APIKey, string, error) {
+	plain, prefix, hash, err := generateAPIKey()
+	if err != nil {
+		s.log(ctx).Error("Error generating api key", zap.Error(err))
+		return nil, "", app.ErrInternal
+	}
+
+	key.Prefix = prefix
+	created, err := s.repo.CreateAPIKey(ctx, key, hash)
+	if err != nil {
+		return nil, "", err
+	}
+
+	s.log(ctx).Info("Created api key", zap.Uint("id", created.ID), zap.String("name", created.Name))
+	return created, plain, nil
+}
+
+func (s *Service) GetAPIKeys(ctx context.Context) ([]*domain.APIKey, error) {
+	return s.repo.GetAPIKeys(ctx)
+}
+
+// RotateAPIKey issues a new key in place of the active one, keeping its name, scopes and expiry.
+func (s *Service) RotateAPIKey(ctx context.Context, id uint) (*domain.APIKey, string, error) {
+	plain, prefix, hash, err := generateAPIKey()
+	if err != nil {
+		s.log(ctx).Error("Error generating api key", zap.Error(err))
+		return nil, "", app.ErrInternal
+	}
+
+	rotated, err := s.repo.RotateAPIKey(ctx, id, prefix, hash)
+	if err != nil {
+		return nil, "", err
+	}
+
+	s.log(ctx).Info("Rotated api key", zap.Uint("id", rotated.ID), zap.String("name", rotated.Name))
+	return rotated, plain, nil
+}
+
+func (s *Service) RevokeAPIKey(ctx context.Context, id uint) error {
+	if err := s.repo.RevokeAPIKey(ctx, id); err != nil {
+		return err
+	}
+
+	s.log(ctx).Info("Revoked api key", zap.Uint("id", id))
+	return nil
+}
+
+// VerifyAPIKey checks that the key is known and active, records its usage
+// and returns the identity of the key, bound to the tenant it was created in.
+func (s *Service) VerifyAPIKey(ctx context.Context, key string) (auth.Identity, error) {
+	found, err := s.repo.GetAPIKeyByHash(ctx, hashAPIKey(key))
+	if errors.Is(err, app.ErrNotFound) {
+		return auth.Identity{}, app.WrapE(app.ErrUnauthorized, "unknown api key")
+	}
+	if err != nil {
+		return auth.Identity{}, err
+	}
+
+	now := time.Now()
+	if !found.IsActive(now) {
+		return auth.Identity{}, app.WrapE(app.ErrUnauthorized, "api key is revoked or expired")
+	}
+
+	// Failing to record the usage should not deny the request.
+	if found.LastUsedAt == nil || now.Sub(*found.LastUsedAt) >= apiKeyTouchInterval {
+		if err := s.repo.TouchAPIKey(ctx, found.ID, now); err != nil {
+			s.log(ctx).Warn("Error recording api key usage", zap.Uint("id", found.ID), zap.Error(err))
+		}
+	}
+
+	return auth.Identity{
+		Subject: fmt.Sprintf("api_key:%d", found.ID),
+		Scopes:  found.Scopes,
+		Tenant:  found.TenantID,
+	}, nil
+}
+
+// EnsureAdminAPIKey stores the key as an admin key of the context tenant, unless it is
+// stored already. It lets the first admin in when no JWT keys are configured.
+func (s *Service) EnsureAdminAPIKey(ctx context.Context, key string) error {
+	if len(key) < adminAPIKeyMinLength {
+		return fmt.Errorf("admin api key must be at least %d characters", adminAPIKeyMinLength)
+	}
+
+	hash := hashAPIKey(key)
+	_, err := s.repo.GetAPIKeyByHash(ctx, hash)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, app.ErrNotFound) {
+		return err
+	}
+
+	created, err := s.repo.CreateAPIKey(ctx, domain.APIKey{
+		Name:   "admin",
+		Prefix: key[:apiKeyShownPrefix],
+		Scopes: []string{string(auth.RoleAdmin)},
+	}, hash)
+	if err != nil {
+		return err
+	}
+
+	s.log(ctx).Info("Created admin api key", zap.Uint("id", created.ID))
+	return nil
+}
+
+// HasAdminAPIKey reports whether an active key which can manage api keys exists in any tenant.
+func (s *Service) HasAdminAPIKey(ctx context.Context) (bool, error) {
+	return s.repo.HasActiveAPIKey(ctx, adminScopes)
+}
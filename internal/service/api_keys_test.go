@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	app "github.com/maxik12233/task-junior"
+	"github.com/maxik12233/task-junior/internal/domain"
+	"github.com/maxik12233/task-junior/pkg/api/auth"
+	"go.uber.org/zap"
+)
+
+func TestCreateAndRotateAPIKey(t *testing.T) {
+	stored := make(map[string]domain.APIKey)
+	repo := &fakeRepository{
+		createAPIKey: func(ctx context.Context, key domain.APIKey, keyHash string) (*domain.APIKey, error) {
+			key.ID = 1
+			stored[keyHash] = key
+			return &key, nil
+		},
+		rotateAPIKey: func(ctx context.Context, id uint, prefix, keyHash string) (*domain.APIKey, error) {
+			key := domain.APIKey{ID: id, Name: "batch", Prefix: prefix}
+			stored[keyHash] = key
+			return &key, nil
+		},
+	}
+	s := NewService(repo, zap.NewNop(), fakeNameInfo{}, Options{})
+
+	created, plain, err := s.CreateAPIKey(context.Background(), domain.APIKey{Name: "batch", Scopes: []string{"viewer"}})
+	if err != nil {
+		t.Fatalf("CreateAPIKey() error = %v", err)
+	}
+	rotated, rotatedPlain, err := s.RotateAPIKey(context.Background(), created.ID)
+	if err != nil {
+		t.Fatalf("RotateAPIKey() error = %v", err)
+	}
+
+	for _, v := range []struct {
+		key   *domain.APIKey
+		plain string
+	}{{created, plain}, {rotated, rotatedPlain}} {
+		if !strings.HasPrefix(v.plain, apiKeyPrefix) || len(v.plain) != len(apiKeyPrefix)+43 {
+			t.Errorf("key %q is not %s followed by 32 base64 bytes", v.plain, apiKeyPrefix)
+		}
+		if v.key.Prefix != v.plain[:apiKeyShownPrefix] {
+			t.Errorf("prefix = %q, want %q", v.key.Prefix, v.plain[:apiKeyShownPrefix])
+		}
+		// Only the hash of the key is stored
+		if _, ok := stored[hashAPIKey(v.plain)]; !ok {
+			t.Errorf("key %q is not stored by its hash", v.plain)
+		}
+		if _, ok := stored[v.plain]; ok {
+			t.Errorf("key %q is stored in plain text", v.plain)
+		}
+	}
+	if plain == rotatedPlain {
+		t.Error("rotated key is the same")
+	}
+	if got := stored[hashAPIKey(plain)]; got.Name != "batch" || len(got.Scopes) != 1 || got.Scopes[0] != "viewer" {
+		t.Errorf("stored key = %+v", got)
+	}
+}
+
+func TestVerifyAPIKey(t *testing.T) {
+	const plain = "tj_0123456789abcdefghijklmnopqrstuvwxyzABCDEFG"
+	now := time.Now()
+	past, future, recent := now.Add(-time.Hour), now.Add(time.Hour), now.Add(-time.Second)
+
+	tests := []struct {
+		name      string
+		key       *domain.APIKey
+		findErr   error
+		touchErr  error
+		wantErr   error
+		wantTouch bool
+	}{
+		{name: "unknown", findErr: app.ErrNotFound, wantErr: app.ErrUnauthorized},
+		{name: "lookup failure", findErr: app.ErrTimeout, wantErr: app.ErrTimeout},
+		{name: "revoked", key: &domain.APIKey{ID: 4, RevokedAt: &past}, wantErr: app.ErrUnauthorized},
+		{name: "expired", key: &domain.APIKey{ID: 4, ExpiresAt: &past}, wantErr: app.ErrUnauthorized},
+		{name: "first use", key: &domain.APIKey{ID: 4, ExpiresAt: &future}, wantTouch: true},
+		{name: "used long ago", key: &domain.APIKey{ID: 4, LastUsedAt: &past}, wantTouch: true},
+		{name: "used recently", key: &domain.APIKey{ID: 4, LastUsedAt: &recent}},
+		{name: "failed usage record", key: &domain.APIKey{ID: 4}, touchErr: errors.New("read only"), wantTouch: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			touched := false
+			repo := &fakeRepository{
+				getAPIKeyByHash: func(ctx context.Context, keyHash string) (*domain.APIKey, error) {
+					if keyHash != hashAPIKey(plain) {
+						t.Errorf("looked up by %q, want the hash of the key", keyHash)
+					}
+					if tt.findErr != nil {
+						return nil, tt.findErr
+					}
+					key := *tt.key
+					key.Scopes = []string{"editor"}
+					key.TenantID = "team-a"
+					return &key, nil
+				},
+				touchAPIKey: func(ctx context.Context, id uint, usedAt time.Time) error {
+					touched = true
+					return tt.touchErr
+				},
+			}
+			s := NewService(repo, zap.NewNop(), fakeNameInfo{}, Options{})
+
+			identity, err := s.VerifyAPIKey(context.Background(), plain)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifyAPIKey() error = %v, want %v", err, tt.wantErr)
+			}
+			if touched != tt.wantTouch {
+				t.Errorf("usage recorded = %v, want %v", touched, tt.wantTouch)
+			}
+			if err != nil {
+				return
+			}
+
+			want := auth.Identity{Subject: "api_key:4", Scopes: []string{"editor"}, Tenant: "team-a"}
+			if identity.Subject != want.Subject || identity.Tenant != want.Tenant || len(identity.Scopes) != 1 || identity.Scopes[0] != "editor" {
+				t.Errorf("identity = %+v, want %+v", identity, want)
+			}
+		})
+	}
+}
+
+func TestEnsureAdminAPIKey(t *testing.T) {
+	const key = "tj_operator-chosen-admin-key-0123456789"
+
+	tests := []struct {
+		name        string
+		key         string
+		findErr     error
+		wantErr     bool
+		wantCreated bool
+	}{
+		{name: "new", key: key, findErr: app.ErrNotFound, wantCreated: true},
+		{name: "stored", key: key},
+		{name: "lookup failure", key: key, findErr: app.ErrTimeout, wantErr: true},
+		{name: "short", key: "tj_short", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			created := false
+			repo := &fakeRepository{
+				getAPIKeyByHash: func(ctx context.Context, keyHash string) (*domain.APIKey, error) {
+					if tt.findErr != nil {
+						return nil, tt.findErr
+					}
+					return &domain.APIKey{ID: 1}, nil
+				},
+				createAPIKey: func(ctx context.Context, stored domain.APIKey, keyHash string) (*domain.APIKey, error) {
+					created = true
+					if keyHash != hashAPIKey(tt.key) || stored.Prefix != tt.key[:apiKeyShownPrefix] {
+						t.Errorf("stored %q with prefix %q, want the hash and the prefix of the key", keyHash, stored.Prefix)
+					}
+					if len(stored.Scopes) != 1 || stored.Scopes[0] != string(auth.RoleAdmin) {
+						t.Errorf("scopes = %v, want admin", stored.Scopes)
+					}
+					stored.ID = 1
+					return &stored, nil
+				},
+			}
+			s := NewService(repo, zap.NewNop(), fakeNameInfo{}, Options{})
+
+			err := s.EnsureAdminAPIKey(context.Background(), tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EnsureAdminAPIKey() error = %v, want error %v", err, tt.wantErr)
+			}
+			if created != tt.wantCreated {
+				t.Errorf("key created = %v, want %v", created, tt.wantCreated)
+			}
+		})
+	}
+}
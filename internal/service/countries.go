@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	stdsort "sort"
+	"strings"
+
+	app "github.com/maxik12233/task-junior"
+	"github.com/maxik12233/task-junior/internal/repository"
+	"github.com/maxik12233/task-junior/pkg/countries"
+)
+
+// filterOptions resolves the region and continent of the filter into nationality codes.
+func filterOptions(filter PersonFilter) (repository.FilterOptions, error) {
+	if filter.Region == "" && filter.Continent == "" {
+		return nil, nil
+	}
+
+	matched := countries.Filter(filter.Region, filter.Continent)
+	if len(matched) == 0 {
+		return nil, app.WrapE(app.ErrInvalidParamType, "unknown region or continent")
+	}
+
+	nationalities := make([]string, len(matched))
+	for i, v := range matched {
+		nationalities[i] = v.Alpha2
+	}
+
+	return repository.NewFilterOptions(nationalities), nil
+}
+
+// Validate checks that the region and continent of the filter match some countries.
+func (f PersonFilter) Validate() error {
+	_, err := filterOptions(f)
+	return err
+}
+
+// GetPersonStats counts persons matching the filter grouped by nationality, region or continent.
+// Groups are ordered by count descending. Unknown nationalities are counted under an empty key.
+func (s *Service) GetPersonStats(ctx context.Context, groupBy string, filter PersonFilter) (PersonStats, error) {
+
+	groupBy = strings.ToLower(groupBy)
+	var groupKey func(country countries.Country) string
+	switch groupBy {
+	case "":
+		groupBy = GroupByNationality
+		groupKey = func(country countries.Country) string { return country.Alpha2 }
+	case GroupByNationality:
+		groupKey = func(country countries.Country) string { return country.Alpha2 }
+	case GroupByRegion:
+		groupKey = func(country countries.Country) string { return country.Region }
+	case GroupByContinent:
+		groupKey = func(country countries.Country) string { return country.Continent }
+	default:
+		return PersonStats{}, app.WrapE(app.ErrInvalidParamType, "group_by must be one of: nationality, region, continent")
+	}
+
+	OptionFilter, err := filterOptions(filter)
+	if err != nil {
+		return PersonStats{}, err
+	}
+
+	counts, err := s.repo.CountPersonsByNationality(ctx, OptionFilter)
+	if err != nil {
+		return PersonStats{}, err
+	}
+
+	grouped := make(map[string]int)
+	for nationality, count := range counts {
+		key := ""
+		if country, ok := countries.Lookup(nationality); ok {
+			key = groupKey(country)
+		}
+		grouped[key] += int(count)
+	}
+
+	stats := make([]PersonStat, 0, len(grouped))
+	for key, count := range grouped {
+		stats = append(stats, PersonStat{Key: key, Count: count})
+	}
+	stdsort.Slice(stats, func(i, j int) bool {
+		if stats[i].Count != stats[j].Count {
+			return stats[i].Count > stats[j].Count
+		}
+		return stats[i].Key < stats[j].Key
+	})
+
+	return PersonStats{GroupBy: groupBy, Groups: stats}, nil
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	app "github.com/maxik12233/task-junior"
+	"github.com/maxik12233/task-junior/internal/domain"
+	"github.com/maxik12233/task-junior/pkg/api/auth"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultIdempotencyTTL   = 24 * time.Hour
+	defaultIdempotencyLease = 5 * time.Minute
+)
+
+func (s *Service) idempotencyTTL() time.Duration {
+	if s.options.IdempotencyTTL > 0 {
+		return s.options.IdempotencyTTL
+	}
+	return defaultIdempotencyTTL
+}
+
+func (s *Service) idempotencyLease() time.Duration {
+	if s.options.IdempotencyLease > 0 {
+		return s.options.IdempotencyLease
+	}
+	return defaultIdempotencyLease
+}
+
+// BeginIdempotentRequest reserves the key of the authenticated subject for the request.
+// It returns nil if the request is new and must be handled, or the stored response of
+// its first try. The key reused with another payload is rejected with app.ErrUnprocessable,
+// and the key of the request in progress with app.ErrDuplicate, until its lease expires.
+func (s *Service) BeginIdempotentRequest(ctx context.Context, key, requestHash string) (*domain.IdempotencyKey, error) {
+	subject := auth.Subject(ctx)
+	reserved, err := s.repo.ReserveIdempotencyKey(ctx, subject, key, requestHash, time.Now().Add(s.idempotencyTTL()), s.idempotencyLease())
+	if err != nil {
+		return nil, err
+	}
+	if reserved {
+		return nil, nil
+	}
+
+	stored, err := s.repo.GetIdempotencyKey(ctx, subject, key)
+	if err != nil {
+		// Deleted by the failed first request in the meantime
+		return nil, app.WrapE(app.ErrDuplicate, "request with the idempotency key is in progress")
+	}
+	if stored.RequestHash != requestHash {
+		s.log(ctx).Warn("Idempotency key is reused with another payload", zap.String("key", key))
+		return nil, app.WrapE(app.ErrUnprocessable, "idempotency key is already used with another payload")
+	}
+	if !stored.IsCompleted() {
+		return nil, app.WrapE(app.ErrDuplicate, "request with the idempotency key is in progress")
+	}
+
+	s.log(ctx).Info("Replaying idempotent request", zap.String("key", key))
+	return stored, nil
+}
+
+// CompleteIdempotentRequest stores the response to be replayed for the key.
+func (s *Service) CompleteIdempotentRequest(ctx context.Context, key string, statusCode int, contentType string, response []byte) error {
+	return s.repo.SaveIdempotentResponse(ctx, auth.Subject(ctx), key, statusCode, contentType, response)
+}
+
+// CancelIdempotentRequest releases the key of the failed request, so it may be retried.
+func (s *Service) CancelIdempotentRequest(ctx context.Context, key string) error {
+	return s.repo.DeleteIdempotencyKey(ctx, auth.Subject(ctx), key)
+}
+
+func (s *Service) DeleteExpiredIdempotencyKeys(ctx context.Context) error {
+	deleted, err := s.repo.DeleteExpiredIdempotencyKeys(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if deleted > 0 {
+		s.log(ctx).Info("Deleted expired idempotency keys", zap.Int64("count", deleted))
+	}
+	return nil
+}
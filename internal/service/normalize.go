@@ -0,0 +1,58 @@
+package service
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/maxik12233/task-junior/internal/domain"
+	"github.com/maxik12233/task-junior/pkg/translit"
+	"golang.org/x/text/unicode/norm"
+)
+
+// originalPipeline cleans up the name as it was entered without changing its script.
+var originalPipeline = []func(string) string{
+	norm.NFC.String,
+	collapseWhitespace,
+}
+
+// normalizedPipeline produces the form which is sent to the foreign api and used for matching.
+var normalizedPipeline = []func(string) string{
+	translit.ICAO,
+	stripCombiningMarks,
+	strings.ToLower,
+}
+
+func collapseWhitespace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// stripCombiningMarks removes marks left after NFC, like stress marks over vowels.
+func stripCombiningMarks(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Mn, r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+func runPipeline(s string, pipeline []func(string) string) string {
+	for _, step := range pipeline {
+		s = step(s)
+	}
+	return s
+}
+
+// normalizeName returns the cleaned up original name and its normalized latin form.
+func normalizeName(name string) (string, string) {
+	original := runPipeline(name, originalPipeline)
+	return original, runPipeline(original, normalizedPipeline)
+}
+
+// NormalizePerson fills normalized forms of the person's name, surname and patronymic.
+func NormalizePerson(person domain.Person) domain.Person {
+	person.Name, person.NameNormalized = normalizeName(person.Name)
+	person.Surname, person.SurnameNormalized = normalizeName(person.Surname)
+	person.Patronymic, person.PatronymicNormalized = normalizeName(person.Patronymic)
+	return person
+}
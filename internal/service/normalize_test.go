@@ -0,0 +1,48 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/maxik12233/task-junior/internal/domain"
+)
+
+func TestNormalizeName(t *testing.T) {
+	tests := []struct {
+		name           string
+		in             string
+		wantOriginal   string
+		wantNormalized string
+	}{
+		{name: "cyrillic", in: "Иван", wantOriginal: "Иван", wantNormalized: "ivan"},
+		{name: "latin", in: "John", wantOriginal: "John", wantNormalized: "john"},
+		{name: "whitespace", in: "  Анна   Мария ", wantOriginal: "Анна Мария", wantNormalized: "anna mariia"},
+		{name: "decomposed letter", in: "Андреи\u0306", wantOriginal: "Андрей", wantNormalized: "andrei"},
+		{name: "stress mark", in: "Ива\u0301н", wantOriginal: "Ива\u0301н", wantNormalized: "ivan"},
+		{name: "empty", in: "", wantOriginal: "", wantNormalized: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original, normalized := normalizeName(tt.in)
+			if original != tt.wantOriginal || normalized != tt.wantNormalized {
+				t.Errorf("normalizeName(%q) = %q, %q, want %q, %q", tt.in, original, normalized, tt.wantOriginal, tt.wantNormalized)
+			}
+		})
+	}
+}
+
+func TestNormalizePerson(t *testing.T) {
+	person := NormalizePerson(domain.Person{Name: " Пётр ", Surname: "Щукин", Patronymic: "Ильич"})
+
+	want := domain.Person{
+		Name:                 "Пётр",
+		Surname:              "Щукин",
+		Patronymic:           "Ильич",
+		NameNormalized:       "petr",
+		SurnameNormalized:    "shchukin",
+		PatronymicNormalized: "ilich",
+	}
+	if person != want {
+		t.Errorf("NormalizePerson() = %+v, want %+v", person, want)
+	}
+}
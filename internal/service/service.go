@@ -3,23 +3,45 @@ package service
 import (
 	"context"
 	"errors"
+	"strings"
 
 	app "github.com/maxik12233/task-junior"
 	"github.com/maxik12233/task-junior/internal/domain"
 	"github.com/maxik12233/task-junior/internal/repository"
+	"github.com/maxik12233/task-junior/pkg/api/auth"
 	"github.com/maxik12233/task-junior/pkg/api/paginate"
 	"github.com/maxik12233/task-junior/pkg/api/sort"
+	"github.com/maxik12233/task-junior/pkg/logger"
 	"github.com/maxik12233/task-junior/pkg/name_info_sdk"
+	"github.com/maxik12233/task-junior/pkg/tenant"
 	"go.uber.org/zap"
 )
 
 type IService interface {
 	CreatePersonInfo(ctx context.Context, person domain.Person) (CombinedInfo, error)
+	ImportPersonInfo(ctx context.Context, person domain.Person) (CombinedInfo, error)
 	DeletePersonInfo(ctx context.Context, id int) error
 	UpdatePersonInfo(ctx context.Context, person domain.Person, char domain.Characteristic) error
-	GetAllPersonInfo(ctx context.Context, sortOption *sort.Options, paginateOption *paginate.Options) ([]*domain.Person, error)
+	GetAllPersonInfo(ctx context.Context, sortOption *sort.Options, paginateOption *paginate.Options, filter PersonFilter) ([]*domain.Person, error)
 	GetPersonInfo(ctx context.Context, id uint) (*domain.Person, error)
-	GetPersonCount(ctx context.Context) (int, error)
+	GetPersonCount(ctx context.Context, filter PersonFilter) (int, error)
+	GetPersonStats(ctx context.Context, groupBy string, filter PersonFilter) (PersonStats, error)
+	ExportPersonInfo(ctx context.Context, sortOption *sort.Options, filter PersonFilter, fn func(person *domain.Person) error) error
+	MergePersonInfo(ctx context.Context, sourceId, targetId uint) (*domain.Person, error)
+	SearchPersonInfo(ctx context.Context, query string, paginateOption *paginate.Options) ([]*domain.PersonSearchResult, int, error)
+
+	CreateAPIKey(ctx context.Context, key domain.APIKey) (*domain.APIKey, string, error)
+	GetAPIKeys(ctx context.Context) ([]*domain.APIKey, error)
+	RotateAPIKey(ctx context.Context, id uint) (*domain.APIKey, string, error)
+	RevokeAPIKey(ctx context.Context, id uint) error
+	VerifyAPIKey(ctx context.Context, key string) (auth.Identity, error)
+	EnsureAdminAPIKey(ctx context.Context, key string) error
+	HasAdminAPIKey(ctx context.Context) (bool, error)
+
+	BeginIdempotentRequest(ctx context.Context, key, requestHash string) (*domain.IdempotencyKey, error)
+	CompleteIdempotentRequest(ctx context.Context, key string, statusCode int, contentType string, response []byte) error
+	CancelIdempotentRequest(ctx context.Context, key string) error
+	DeleteExpiredIdempotencyKeys(ctx context.Context) error
 }
 
 //
@@ -28,18 +50,30 @@ type Service struct {
 	repo          repository.IRepository
 	logger        *zap.Logger
 	byNameService name_info_sdk.INameInfo
+	options       Options
 }
 
-func NewService(repo repository.IRepository, logger *zap.Logger, byNameService name_info_sdk.INameInfo) IService {
+func NewService(repo repository.IRepository, logger *zap.Logger, byNameService name_info_sdk.INameInfo, options Options) IService {
 	return &Service{
 		repo:          repo,
 		logger:        logger,
 		byNameService: byNameService,
+		options:       options,
 	}
 }
 
-func (s *Service) GetPersonCount(ctx context.Context) (int, error) {
-	count, err := s.repo.GetPersonCount(ctx)
+// log returns the request-scoped logger of the context, or the service logger.
+func (s *Service) log(ctx context.Context) *zap.Logger {
+	return logger.FromContext(ctx, s.logger)
+}
+
+func (s *Service) GetPersonCount(ctx context.Context, filter PersonFilter) (int, error) {
+	OptionFilter, err := filterOptions(filter)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := s.repo.GetPersonCount(ctx, OptionFilter)
 	if err != nil {
 		return 0, err
 	}
@@ -56,7 +90,7 @@ func (s *Service) GetPersonInfo(ctx context.Context, id uint) (*domain.Person, e
 	return person, nil
 }
 
-func (s *Service) GetAllPersonInfo(ctx context.Context, sortOption *sort.Options, paginateOption *paginate.Options) ([]*domain.Person, error) {
+func (s *Service) GetAllPersonInfo(ctx context.Context, sortOption *sort.Options, paginateOption *paginate.Options, filter PersonFilter) ([]*domain.Person, error) {
 
 	var (
 		OptionSort     repository.SortOptions
@@ -71,7 +105,12 @@ func (s *Service) GetAllPersonInfo(ctx context.Context, sortOption *sort.Options
 		OptionPaginate = repository.NewPaginateOptions(paginateOption.Page, paginateOption.PerPage)
 	}
 
-	persons, err := s.repo.GetPersonAll(ctx, OptionSort, OptionPaginate)
+	OptionFilter, err := filterOptions(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	persons, err := s.repo.GetPersonAll(ctx, OptionSort, OptionPaginate, OptionFilter)
 	if err != nil {
 		return nil, err
 	}
@@ -79,13 +118,101 @@ func (s *Service) GetAllPersonInfo(ctx context.Context, sortOption *sort.Options
 	return persons, nil
 }
 
+func (s *Service) SearchPersonInfo(ctx context.Context, query string, paginateOption *paginate.Options) ([]*domain.PersonSearchResult, int, error) {
+
+	if strings.TrimSpace(query) == "" {
+		return nil, 0, app.WrapE(app.ErrNotAllRequiredQueries, "search query is empty")
+	}
+
+	var OptionPaginate repository.PaginateOptions
+	if paginateOption != nil {
+		OptionPaginate = repository.NewPaginateOptions(paginateOption.Page, paginateOption.PerPage)
+	}
+
+	query, normalizedQuery := normalizeName(query)
+	results, count, err := s.repo.SearchPersons(ctx, query, normalizedQuery, s.options.SearchSimilarity, OptionPaginate)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return results, int(count), nil
+}
+
+func (s *Service) ExportPersonInfo(ctx context.Context, sortOption *sort.Options, filter PersonFilter, fn func(person *domain.Person) error) error {
+
+	var OptionSort repository.SortOptions
+	if sortOption != nil {
+		OptionSort = repository.NewSortOptions(sortOption.Field, sortOption.Order)
+	}
+
+	OptionFilter, err := filterOptions(filter)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.IteratePersons(ctx, OptionSort, OptionFilter, fn)
+}
+
 func (s *Service) CreatePersonInfo(ctx context.Context, person domain.Person) (CombinedInfo, error) {
 
-	info, err := s.fetchAllNameInfo(person.Name)
+	person = NormalizePerson(person)
+
+	duplicateOf, err := s.checkDuplicates(ctx, person)
+	if err != nil {
+		return CombinedInfo{}, err
+	}
+
+	info, err := s.fetchAllNameInfo(ctx, person.NameNormalized)
 	if err != nil {
-		s.logger.Error("Error fetching data from foreign api", zap.Error(err))
+		s.log(ctx).Error("Error fetching data from foreign api", zap.Error(err))
 		return CombinedInfo{}, app.ErrInternal
 	}
+	info.Name = person.Name
+	info.DuplicateOf = duplicateOf
+
+	if err := s.repo.CreatePerson(ctx, person, info.ToDomainCharactaristic()); err != nil {
+		return CombinedInfo{}, err
+	}
+
+	return info, nil
+}
+
+// ImportPersonInfo creates person keeping the given characteristics and
+// fetching only the missing ones from the foreign api.
+func (s *Service) ImportPersonInfo(ctx context.Context, person domain.Person) (CombinedInfo, error) {
+	person = NormalizePerson(person)
+
+	duplicateOf, err := s.checkDuplicates(ctx, person)
+	if err != nil {
+		return CombinedInfo{}, err
+	}
+
+	char := person.Characteristic
+	info := CombinedInfo{
+		Name:        person.Name,
+		Age:         char.Age,
+		Gender:      char.Gender,
+		Nationality: char.Nationality,
+		DuplicateOf: duplicateOf,
+	}
+
+	if char.Age == 0 || char.Gender == "" || char.Nationality == "" {
+		fetched, err := s.fetchAllNameInfo(ctx, person.NameNormalized)
+		if err != nil {
+			s.log(ctx).Error("Error fetching data from foreign api", zap.Error(err))
+			return CombinedInfo{}, app.ErrInternal
+		}
+
+		if info.Age == 0 {
+			info.Age = fetched.Age
+		}
+		if info.Gender == "" {
+			info.Gender = fetched.Gender
+		}
+		if info.Nationality == "" {
+			info.Nationality = fetched.Nationality
+		}
+	}
 
 	if err := s.repo.CreatePerson(ctx, person, info.ToDomainCharactaristic()); err != nil {
 		return CombinedInfo{}, err
@@ -105,6 +232,7 @@ func (s *Service) DeletePersonInfo(ctx context.Context, id int) error {
 
 func (s *Service) UpdatePersonInfo(ctx context.Context, person domain.Person, char domain.Characteristic) error {
 
+	person = NormalizePerson(person)
 	if err := s.repo.UpdatePerson(ctx, person, char); err != nil {
 		return err
 	}
@@ -112,7 +240,62 @@ func (s *Service) UpdatePersonInfo(ctx context.Context, person domain.Person, ch
 	return nil
 }
 
-func (s *Service) fetchAllNameInfo(name string) (CombinedInfo, error) {
+func (s *Service) MergePersonInfo(ctx context.Context, sourceId, targetId uint) (*domain.Person, error) {
+
+	if sourceId == targetId {
+		return nil, app.WrapE(app.ErrBadRequest, "can't merge person into itself")
+	}
+
+	person, err := s.repo.MergePerson(ctx, sourceId, targetId)
+	if err != nil {
+		return nil, err
+	}
+
+	s.log(ctx).Info("Persons merged", zap.Uint("source_id", sourceId), zap.Uint("target_id", targetId))
+	return person, nil
+}
+
+// checkDuplicates looks for persons with a similar name according to the duplicate policy.
+// Returns ids of the found duplicates, or app.ErrDuplicate if the policy rejects them.
+func (s *Service) checkDuplicates(ctx context.Context, person domain.Person) ([]uint, error) {
+	if s.options.Duplicates.Policy == DuplicateAllow || s.options.Duplicates.Policy == "" {
+		return nil, nil
+	}
+
+	duplicates, err := s.repo.FindDuplicates(ctx, person,
+		repository.NewMatchOptions(s.options.Duplicates.Match, s.options.Duplicates.Threshold))
+	if err != nil {
+		return nil, err
+	}
+	if len(duplicates) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uint, len(duplicates))
+	for i, v := range duplicates {
+		ids[i] = v.ID
+	}
+
+	if s.options.Duplicates.Policy == DuplicateReject {
+		s.log(ctx).Info("Rejected duplicate person", zap.String("name", person.NormalizedFullName()), zap.Uints("duplicate_of", ids))
+		return ids, app.ErrDuplicate
+	}
+
+	s.log(ctx).Warn("Creating possible duplicate person", zap.String("name", person.NormalizedFullName()), zap.Uints("duplicate_of", ids))
+	return ids, nil
+}
+
+// nameInfo returns the enrichment provider of the tenant of the context.
+func (s *Service) nameInfo(ctx context.Context) name_info_sdk.INameInfo {
+	if byNameService, ok := s.options.TenantNameInfo[tenant.ID(ctx)]; ok {
+		return byNameService
+	}
+	return s.byNameService
+}
+
+func (s *Service) fetchAllNameInfo(ctx context.Context, name string) (CombinedInfo, error) {
+	byNameService := s.nameInfo(ctx)
+
 	var (
 		ageChan    = make(chan *name_info_sdk.LikelyAge)
 		genderChan = make(chan *name_info_sdk.LikelyGender)
@@ -120,9 +303,9 @@ func (s *Service) fetchAllNameInfo(name string) (CombinedInfo, error) {
 	)
 
 	go func() {
-		resp, err := s.byNameService.GetAgeInfoByName(name)
+		resp, err := byNameService.GetAgeInfoByName(ctx, name)
 		if err != nil {
-			s.logger.Error("Error while fetching age info", zap.Error(err))
+			s.log(ctx).Error("Error while fetching age info", zap.Error(err))
 			ageChan <- nil
 			return
 		}
@@ -130,9 +313,9 @@ func (s *Service) fetchAllNameInfo(name string) (CombinedInfo, error) {
 		ageChan <- resp
 	}()
 	go func() {
-		resp, err := s.byNameService.GetGenderInfoByName(name)
+		resp, err := byNameService.GetGenderInfoByName(ctx, name)
 		if err != nil {
-			s.logger.Error("Error while fetching gender info", zap.Error(err))
+			s.log(ctx).Error("Error while fetching gender info", zap.Error(err))
 			genderChan <- nil
 			return
 		}
@@ -140,9 +323,9 @@ func (s *Service) fetchAllNameInfo(name string) (CombinedInfo, error) {
 		genderChan <- resp
 	}()
 	go func() {
-		resp, err := s.byNameService.GetLikelyNationalityInfoByName(name)
+		resp, err := byNameService.GetLikelyNationalityInfoByName(ctx, name)
 		if err != nil {
-			s.logger.Error("Error while fetching nationality info", zap.Error(err))
+			s.log(ctx).Error("Error while fetching nationality info", zap.Error(err))
 			natChan <- nil
 			return
 		}
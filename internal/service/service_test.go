@@ -0,0 +1,318 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	app "github.com/maxik12233/task-junior"
+	"github.com/maxik12233/task-junior/internal/domain"
+	"github.com/maxik12233/task-junior/internal/repository"
+	"github.com/maxik12233/task-junior/pkg/api/paginate"
+	"github.com/maxik12233/task-junior/pkg/name_info_sdk"
+	"go.uber.org/zap"
+)
+
+// fakeRepository implements the repository methods used by the service tests.
+// The methods without a func panic on the nil embedded interface.
+type fakeRepository struct {
+	repository.IRepository
+
+	createPerson       func(ctx context.Context, person domain.Person, char domain.Characteristic) error
+	findDuplicates     func(ctx context.Context, person domain.Person, matchOptions repository.MatchOptions) ([]*domain.Person, error)
+	mergePerson        func(ctx context.Context, sourceId, targetId uint) (*domain.Person, error)
+	countByNationality func(ctx context.Context, filterOptions repository.FilterOptions) (map[string]int64, error)
+	createAPIKey       func(ctx context.Context, key domain.APIKey, keyHash string) (*domain.APIKey, error)
+	getAPIKeyByHash    func(ctx context.Context, keyHash string) (*domain.APIKey, error)
+	rotateAPIKey       func(ctx context.Context, id uint, prefix, keyHash string) (*domain.APIKey, error)
+	touchAPIKey        func(ctx context.Context, id uint, usedAt time.Time) error
+	searchPersons      func(ctx context.Context, query, normalizedQuery string, similarity float64, paginateOptions repository.PaginateOptions) ([]*domain.PersonSearchResult, int64, error)
+}
+
+func (r *fakeRepository) CreatePerson(ctx context.Context, person domain.Person, char domain.Characteristic) error {
+	return r.createPerson(ctx, person, char)
+}
+
+func (r *fakeRepository) FindDuplicates(ctx context.Context, person domain.Person, matchOptions repository.MatchOptions) ([]*domain.Person, error) {
+	return r.findDuplicates(ctx, person, matchOptions)
+}
+
+func (r *fakeRepository) MergePerson(ctx context.Context, sourceId, targetId uint) (*domain.Person, error) {
+	return r.mergePerson(ctx, sourceId, targetId)
+}
+
+func (r *fakeRepository) SearchPersons(ctx context.Context, query, normalizedQuery string, similarity float64, paginateOptions repository.PaginateOptions) ([]*domain.PersonSearchResult, int64, error) {
+	return r.searchPersons(ctx, query, normalizedQuery, similarity, paginateOptions)
+}
+
+func (r *fakeRepository) CountPersonsByNationality(ctx context.Context, filterOptions repository.FilterOptions) (map[string]int64, error) {
+	return r.countByNationality(ctx, filterOptions)
+}
+
+func (r *fakeRepository) CreateAPIKey(ctx context.Context, key domain.APIKey, keyHash string) (*domain.APIKey, error) {
+	return r.createAPIKey(ctx, key, keyHash)
+}
+
+func (r *fakeRepository) GetAPIKeyByHash(ctx context.Context, keyHash string) (*domain.APIKey, error) {
+	return r.getAPIKeyByHash(ctx, keyHash)
+}
+
+func (r *fakeRepository) RotateAPIKey(ctx context.Context, id uint, prefix, keyHash string) (*domain.APIKey, error) {
+	return r.rotateAPIKey(ctx, id, prefix, keyHash)
+}
+
+func (r *fakeRepository) TouchAPIKey(ctx context.Context, id uint, usedAt time.Time) error {
+	return r.touchAPIKey(ctx, id, usedAt)
+}
+
+// fakeNameInfo answers every name with the same characteristics.
+type fakeNameInfo struct {
+	name_info_sdk.INameInfo
+}
+
+func (fakeNameInfo) GetAgeInfoByName(ctx context.Context, name string) (*name_info_sdk.LikelyAge, error) {
+	return &name_info_sdk.LikelyAge{Name: name, Age: 30}, nil
+}
+
+func (fakeNameInfo) GetGenderInfoByName(ctx context.Context, name string) (*name_info_sdk.LikelyGender, error) {
+	return &name_info_sdk.LikelyGender{Name: name, Gender: "male"}, nil
+}
+
+func (fakeNameInfo) GetLikelyNationalityInfoByName(ctx context.Context, name string) (*name_info_sdk.LikelyNationality, error) {
+	return &name_info_sdk.LikelyNationality{Name: name, Nationality: "RU"}, nil
+}
+
+func TestCreatePersonInfoDuplicates(t *testing.T) {
+	duplicates := []*domain.Person{{ID: 3}, {ID: 7}}
+
+	tests := []struct {
+		name        string
+		policy      DuplicatePolicy
+		found       []*domain.Person
+		wantErr     error
+		wantLookup  bool
+		wantCreated bool
+		wantDupOf   []uint
+	}{
+		{name: "no policy", found: duplicates, wantCreated: true},
+		{name: "allow", policy: DuplicateAllow, found: duplicates, wantCreated: true},
+		{name: "warn without duplicates", policy: DuplicateWarn, wantLookup: true, wantCreated: true},
+		{name: "warn", policy: DuplicateWarn, found: duplicates, wantLookup: true, wantCreated: true, wantDupOf: []uint{3, 7}},
+		{name: "reject without duplicates", policy: DuplicateReject, wantLookup: true, wantCreated: true},
+		{name: "reject", policy: DuplicateReject, found: duplicates, wantErr: app.ErrDuplicate, wantLookup: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			looked, created := false, false
+			repo := &fakeRepository{
+				findDuplicates: func(ctx context.Context, person domain.Person, matchOptions repository.MatchOptions) ([]*domain.Person, error) {
+					looked = true
+					if person.NameNormalized != "ivan" || person.SurnameNormalized != "ivanov" {
+						t.Errorf("duplicates of not normalized person %+v", person)
+					}
+					if matchOptions.GetMethod() != repository.MatchTrigram || matchOptions.GetThreshold() != 0.8 {
+						t.Errorf("match options = %s %v, want trigram 0.8", matchOptions.GetMethod(), matchOptions.GetThreshold())
+					}
+					return tt.found, nil
+				},
+				createPerson: func(ctx context.Context, person domain.Person, char domain.Characteristic) error {
+					created = true
+					return nil
+				},
+			}
+			s := NewService(repo, zap.NewNop(), fakeNameInfo{}, Options{
+				Duplicates: DuplicateOptions{Policy: tt.policy, Match: repository.MatchTrigram, Threshold: 0.8},
+			})
+
+			info, err := s.CreatePersonInfo(context.Background(), domain.Person{Name: "Иван", Surname: "Иванов"})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreatePersonInfo() error = %v, want %v", err, tt.wantErr)
+			}
+			if looked != tt.wantLookup {
+				t.Errorf("duplicates looked up = %v, want %v", looked, tt.wantLookup)
+			}
+			if created != tt.wantCreated {
+				t.Errorf("person created = %v, want %v", created, tt.wantCreated)
+			}
+			if len(info.DuplicateOf) != len(tt.wantDupOf) {
+				t.Fatalf("DuplicateOf = %v, want %v", info.DuplicateOf, tt.wantDupOf)
+			}
+			for i := range tt.wantDupOf {
+				if info.DuplicateOf[i] != tt.wantDupOf[i] {
+					t.Errorf("DuplicateOf = %v, want %v", info.DuplicateOf, tt.wantDupOf)
+				}
+			}
+		})
+	}
+}
+
+func TestMergePersonInfo(t *testing.T) {
+	tests := []struct {
+		name       string
+		sourceId   uint
+		targetId   uint
+		mergeErr   error
+		wantErr    error
+		wantMerged bool
+	}{
+		{name: "merged", sourceId: 2, targetId: 1, wantMerged: true},
+		{name: "into itself", sourceId: 1, targetId: 1, wantErr: app.ErrBadRequest},
+		{name: "unknown person", sourceId: 2, targetId: 9, mergeErr: app.ErrNotFound, wantErr: app.ErrNotFound, wantMerged: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged := false
+			repo := &fakeRepository{
+				mergePerson: func(ctx context.Context, sourceId, targetId uint) (*domain.Person, error) {
+					merged = true
+					if sourceId != tt.sourceId || targetId != tt.targetId {
+						t.Errorf("merged %d into %d, want %d into %d", sourceId, targetId, tt.sourceId, tt.targetId)
+					}
+					if tt.mergeErr != nil {
+						return nil, tt.mergeErr
+					}
+					return &domain.Person{ID: targetId}, nil
+				},
+			}
+			s := NewService(repo, zap.NewNop(), fakeNameInfo{}, Options{})
+
+			person, err := s.MergePersonInfo(context.Background(), tt.sourceId, tt.targetId)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("MergePersonInfo() error = %v, want %v", err, tt.wantErr)
+			}
+			if merged != tt.wantMerged {
+				t.Errorf("repository merge called = %v, want %v", merged, tt.wantMerged)
+			}
+			if err == nil && person.ID != tt.targetId {
+				t.Errorf("merged person id = %d, want %d", person.ID, tt.targetId)
+			}
+		})
+	}
+}
+
+func TestSearchPersonInfo(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		wantErr        error
+		wantQuery      string
+		wantNormalized string
+	}{
+		{name: "cyrillic", query: " Иван  Иванов ", wantQuery: "Иван Иванов", wantNormalized: "ivan ivanov"},
+		{name: "latin", query: "Ivan", wantQuery: "Ivan", wantNormalized: "ivan"},
+		{name: "empty", query: "", wantErr: app.ErrNotAllRequiredQueries},
+		{name: "whitespace", query: " \t ", wantErr: app.ErrNotAllRequiredQueries},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRepository{
+				searchPersons: func(ctx context.Context, query, normalizedQuery string, similarity float64, paginateOptions repository.PaginateOptions) ([]*domain.PersonSearchResult, int64, error) {
+					if query != tt.wantQuery || normalizedQuery != tt.wantNormalized {
+						t.Errorf("searched %q, %q, want %q, %q", query, normalizedQuery, tt.wantQuery, tt.wantNormalized)
+					}
+					if similarity != 0.4 {
+						t.Errorf("similarity = %v, want 0.4", similarity)
+					}
+					if paginateOptions.GetPage() != 2 || paginateOptions.GetPerPage() != 5 {
+						t.Errorf("page %d of %d, want 2 of 5", paginateOptions.GetPage(), paginateOptions.GetPerPage())
+					}
+					return []*domain.PersonSearchResult{{Person: domain.Person{ID: 1}, Score: 0.9}}, 11, nil
+				},
+			}
+			s := NewService(repo, zap.NewNop(), fakeNameInfo{}, Options{SearchSimilarity: 0.4})
+
+			results, count, err := s.SearchPersonInfo(context.Background(), tt.query, &paginate.Options{Page: 2, PerPage: 5})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SearchPersonInfo() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && (len(results) != 1 || count != 11) {
+				t.Errorf("SearchPersonInfo() = %d results of %d, want 1 of 11", len(results), count)
+			}
+		})
+	}
+}
+
+func TestGetPersonStats(t *testing.T) {
+	counts := map[string]int64{"RU": 5, "UA": 2, "DE": 3, "KZ": 1, "": 4, "XX": 1}
+
+	tests := []struct {
+		name       string
+		groupBy    string
+		filter     PersonFilter
+		wantErr    error
+		wantGroup  string
+		want       []PersonStat
+		wantFilter []string
+	}{
+		{
+			name:      "nationality by default",
+			wantGroup: GroupByNationality,
+			want:      []PersonStat{{Key: "", Count: 5}, {Key: "RU", Count: 5}, {Key: "DE", Count: 3}, {Key: "UA", Count: 2}, {Key: "KZ", Count: 1}},
+		},
+		{
+			name:      "region",
+			groupBy:   "Region",
+			wantGroup: GroupByRegion,
+			want:      []PersonStat{{Key: "Eastern Europe", Count: 7}, {Key: "", Count: 5}, {Key: "Western Europe", Count: 3}, {Key: "Central Asia", Count: 1}},
+		},
+		{
+			name:      "continent",
+			groupBy:   GroupByContinent,
+			wantGroup: GroupByContinent,
+			want:      []PersonStat{{Key: "Europe", Count: 10}, {Key: "", Count: 5}, {Key: "Asia", Count: 1}},
+		},
+		{
+			name:       "filtered",
+			groupBy:    GroupByNationality,
+			filter:     PersonFilter{Region: "central asia"},
+			wantGroup:  GroupByNationality,
+			want:       []PersonStat{{Key: "", Count: 5}, {Key: "RU", Count: 5}, {Key: "DE", Count: 3}, {Key: "UA", Count: 2}, {Key: "KZ", Count: 1}},
+			wantFilter: []string{"KG", "KZ", "TJ", "TM", "UZ"},
+		},
+		{name: "unknown group", groupBy: "planet", wantErr: app.ErrInvalidParamType},
+		{name: "unknown region", filter: PersonFilter{Region: "Atlantis"}, wantErr: app.ErrInvalidParamType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRepository{
+				countByNationality: func(ctx context.Context, filterOptions repository.FilterOptions) (map[string]int64, error) {
+					var got []string
+					if filterOptions != nil {
+						got = filterOptions.GetNationalities()
+					}
+					if strings.Join(got, ",") != strings.Join(tt.wantFilter, ",") {
+						t.Errorf("nationalities filter = %v, want %v", got, tt.wantFilter)
+					}
+					return counts, nil
+				},
+			}
+			s := NewService(repo, zap.NewNop(), fakeNameInfo{}, Options{})
+
+			stats, err := s.GetPersonStats(context.Background(), tt.groupBy, tt.filter)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetPersonStats() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if stats.GroupBy != tt.wantGroup {
+				t.Errorf("GroupBy = %q, want %q", stats.GroupBy, tt.wantGroup)
+			}
+			if len(stats.Groups) != len(tt.want) {
+				t.Fatalf("groups = %+v, want %+v", stats.Groups, tt.want)
+			}
+			for i := range tt.want {
+				if stats.Groups[i] != tt.want[i] {
+					t.Errorf("groups = %+v, want %+v", stats.Groups, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
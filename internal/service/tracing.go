@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+
+	"github.com/maxik12233/task-junior/internal/domain"
+	"github.com/maxik12233/task-junior/pkg/api/auth"
+	"github.com/maxik12233/task-junior/pkg/api/paginate"
+	"github.com/maxik12233/task-junior/pkg/api/sort"
+	"github.com/maxik12233/task-junior/pkg/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/maxik12233/task-junior/internal/service")
+
+// tracedService makes every call of the service a span named after the method.
+type tracedService struct {
+	next IService
+}
+
+// WithTracing returns the service with its methods traced.
+func WithTracing(svc IService) IService {
+	return &tracedService{next: svc}
+}
+
+func (s *tracedService) start(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "Service."+method, trace.WithAttributes(attrs...))
+}
+
+func (s *tracedService) CreatePersonInfo(ctx context.Context, person domain.Person) (CombinedInfo, error) {
+	ctx, span := s.start(ctx, "CreatePersonInfo")
+	info, err := s.next.CreatePersonInfo(ctx, person)
+	tracing.End(span, err)
+	return info, err
+}
+
+func (s *tracedService) ImportPersonInfo(ctx context.Context, person domain.Person) (CombinedInfo, error) {
+	ctx, span := s.start(ctx, "ImportPersonInfo")
+	info, err := s.next.ImportPersonInfo(ctx, person)
+	tracing.End(span, err)
+	return info, err
+}
+
+func (s *tracedService) DeletePersonInfo(ctx context.Context, id int) error {
+	ctx, span := s.start(ctx, "DeletePersonInfo", attribute.Int("person.id", id))
+	err := s.next.DeletePersonInfo(ctx, id)
+	tracing.End(span, err)
+	return err
+}
+
+func (s *tracedService) UpdatePersonInfo(ctx context.Context, person domain.Person, char domain.Characteristic) error {
+	ctx, span := s.start(ctx, "UpdatePersonInfo", attribute.Int("person.id", int(person.ID)))
+	err := s.next.UpdatePersonInfo(ctx, person, char)
+	tracing.End(span, err)
+	return err
+}
+
+func (s *tracedService) GetAllPersonInfo(ctx context.Context, sortOption *sort.Options, paginateOption *paginate.Options, filter PersonFilter) ([]*domain.Person, error) {
+	ctx, span := s.start(ctx, "GetAllPersonInfo")
+	persons, err := s.next.GetAllPersonInfo(ctx, sortOption, paginateOption, filter)
+	tracing.End(span, err)
+	return persons, err
+}
+
+func (s *tracedService) GetPersonInfo(ctx context.Context, id uint) (*domain.Person, error) {
+	ctx, span := s.start(ctx, "GetPersonInfo", attribute.Int("person.id", int(id)))
+	person, err := s.next.GetPersonInfo(ctx, id)
+	tracing.End(span, err)
+	return person, err
+}
+
+func (s *tracedService) GetPersonCount(ctx context.Context, filter PersonFilter) (int, error) {
+	ctx, span := s.start(ctx, "GetPersonCount")
+	count, err := s.next.GetPersonCount(ctx, filter)
+	tracing.End(span, err)
+	return count, err
+}
+
+func (s *tracedService) GetPersonStats(ctx context.Context, groupBy string, filter PersonFilter) (PersonStats, error) {
+	ctx, span := s.start(ctx, "GetPersonStats", attribute.String("group_by", groupBy))
+	stats, err := s.next.GetPersonStats(ctx, groupBy, filter)
+	tracing.End(span, err)
+	return stats, err
+}
+
+func (s *tracedService) ExportPersonInfo(ctx context.Context, sortOption *sort.Options, filter PersonFilter, fn func(person *domain.Person) error) error {
+	ctx, span := s.start(ctx, "ExportPersonInfo")
+	err := s.next.ExportPersonInfo(ctx, sortOption, filter, fn)
+	tracing.End(span, err)
+	return err
+}
+
+func (s *tracedService) MergePersonInfo(ctx context.Context, sourceId, targetId uint) (*domain.Person, error) {
+	ctx, span := s.start(ctx, "MergePersonInfo",
+		attribute.Int("merge.source_id", int(sourceId)),
+		attribute.Int("merge.target_id", int(targetId)),
+	)
+	person, err := s.next.MergePersonInfo(ctx, sourceId, targetId)
+	tracing.End(span, err)
+	return person, err
+}
+
+func (s *tracedService) SearchPersonInfo(ctx context.Context, query string, paginateOption *paginate.Options) ([]*domain.PersonSearchResult, int, error) {
+	ctx, span := s.start(ctx, "SearchPersonInfo")
+	results, count, err := s.next.SearchPersonInfo(ctx, query, paginateOption)
+	tracing.End(span, err)
+	return results, count, err
+}
+
+func (s *tracedService) CreateAPIKey(ctx context.Context, key domain.APIKey) (*domain.APIKey, string, error) {
+	ctx, span := s.start(ctx, "CreateAPIKey")
+	created, plain, err := s.next.CreateAPIKey(ctx, key)
+	tracing.End(span, err)
+	return created, plain, err
+}
+
+func (s *tracedService) GetAPIKeys(ctx context.Context) ([]*domain.APIKey, error) {
+	ctx, span := s.start(ctx, "GetAPIKeys")
+	keys, err := s.next.GetAPIKeys(ctx)
+	tracing.End(span, err)
+	return keys, err
+}
+
+func (s *tracedService) RotateAPIKey(ctx context.Context, id uint) (*domain.APIKey, string, error) {
+	ctx, span := s.start(ctx, "RotateAPIKey", attribute.Int("api_key.id", int(id)))
+	key, plain, err := s.next.RotateAPIKey(ctx, id)
+	tracing.End(span, err)
+	return key, plain, err
+}
+
+func (s *tracedService) RevokeAPIKey(ctx context.Context, id uint) error {
+	ctx, span := s.start(ctx, "RevokeAPIKey", attribute.Int("api_key.id", int(id)))
+	err := s.next.RevokeAPIKey(ctx, id)
+	tracing.End(span, err)
+	return err
+}
+
+func (s *tracedService) VerifyAPIKey(ctx context.Context, key string) (auth.Identity, error) {
+	ctx, span := s.start(ctx, "VerifyAPIKey")
+	identity, err := s.next.VerifyAPIKey(ctx, key)
+	tracing.End(span, err)
+	return identity, err
+}
+
+func (s *tracedService) EnsureAdminAPIKey(ctx context.Context, key string) error {
+	ctx, span := s.start(ctx, "EnsureAdminAPIKey")
+	err := s.next.EnsureAdminAPIKey(ctx, key)
+	tracing.End(span, err)
+	return err
+}
+
+func (s *tracedService) HasAdminAPIKey(ctx context.Context) (bool, error) {
+	ctx, span := s.start(ctx, "HasAdminAPIKey")
+	ok, err := s.next.HasAdminAPIKey(ctx)
+	tracing.End(span, err)
+	return ok, err
+}
+
+func (s *tracedService) BeginIdempotentRequest(ctx context.Context, key, requestHash string) (*domain.IdempotencyKey, error) {
+	ctx, span := s.start(ctx, "BeginIdempotentRequest")
+	stored, err := s.next.BeginIdempotentRequest(ctx, key, requestHash)
+	tracing.End(span, err)
+	return stored, err
+}
+
+func (s *tracedService) CompleteIdempotentRequest(ctx context.Context, key string, statusCode int, contentType string, response []byte) error {
+	ctx, span := s.start(ctx, "CompleteIdempotentRequest")
+	err := s.next.CompleteIdempotentRequest(ctx, key, statusCode, contentType, response)
+	tracing.End(span, err)
+	return err
+}
+
+func (s *tracedService) CancelIdempotentRequest(ctx context.Context, key string) error {
+	ctx, span := s.start(ctx, "CancelIdempotentRequest")
+	err := s.next.CancelIdempotentRequest(ctx, key)
+	tracing.End(span, err)
+	return err
+}
+
+func (s *tracedService) DeleteExpiredIdempotencyKeys(ctx context.Context) error {
+	ctx, span := s.start(ctx, "DeleteExpiredIdempotencyKeys")
+	err := s.next.DeleteExpiredIdempotencyKeys(ctx)
+	tracing.End(span, err)
+	return err
+}
@@ -1,12 +1,94 @@
 package service
 
-import "github.com/maxik12233/task-junior/internal/domain"
+import (
+	"fmt"
+	"time"
+
+	"github.com/maxik12233/task-junior/internal/domain"
+	"github.com/maxik12233/task-junior/internal/repository"
+	"github.com/maxik12233/task-junior/pkg/name_info_sdk"
+)
 
 type CombinedInfo struct {
 	Name        string
 	Age         int
 	Gender      string
 	Nationality string
+	DuplicateOf []uint
+}
+
+type DuplicatePolicy string
+
+const (
+	DuplicateReject DuplicatePolicy = "reject"
+	DuplicateWarn   DuplicatePolicy = "warn"
+	DuplicateAllow  DuplicatePolicy = "allow"
+)
+
+type Options struct {
+	Duplicates DuplicateOptions
+	// SearchSimilarity is the minimal trigram word similarity for search matches.
+	SearchSimilarity float64
+	// TenantNameInfo overrides the enrichment provider for the tenants.
+	TenantNameInfo map[string]name_info_sdk.INameInfo
+	// IdempotencyTTL is how long responses to requests with idempotency keys are kept.
+	IdempotencyTTL time.Duration
+	// IdempotencyLease is how long the key of the request in progress is held. The key
+	// of the request which has not finished by then, e.g. as the process has crashed, is
+	// taken over by the retry, so the lease must be longer than the longest request.
+	IdempotencyLease time.Duration
+}
+
+// DuplicateOptions configures duplicate detection on person creation.
+// Match is one of repository.MatchExact, MatchLevenshtein or MatchTrigram.
+type DuplicateOptions struct {
+	Policy    DuplicatePolicy
+	Match     string
+	Threshold float64
+}
+
+// Validate reports the unknown policy, and the unknown match method unless duplicates are allowed.
+func (o DuplicateOptions) Validate() error {
+	switch o.Policy {
+	case "", DuplicateAllow:
+		return nil
+	case DuplicateReject, DuplicateWarn:
+	default:
+		return fmt.Errorf("unknown duplicate policy %q", o.Policy)
+	}
+
+	switch o.Match {
+	case repository.MatchExact, repository.MatchLevenshtein, repository.MatchTrigram:
+		return nil
+	default:
+		return fmt.Errorf("unknown duplicate match method %q", o.Match)
+	}
+}
+
+// PersonFilter limits persons by the region or continent of their nationality.
+// Empty fields match any person.
+type PersonFilter struct {
+	Region    string
+	Continent string
+}
+
+// Person stats groupings.
+const (
+	GroupByNationality = "nationality"
+	GroupByRegion      = "region"
+	GroupByContinent   = "continent"
+)
+
+// PersonStat is the count of persons in the group.
+type PersonStat struct {
+	Key   string
+	Count int
+}
+
+// PersonStats are the person counts grouped by one of the GroupBy groupings.
+type PersonStats struct {
+	GroupBy string
+	Groups  []PersonStat
 }
 
 func (c *CombinedInfo) ToDomainCharactaristic() domain.Characteristic {
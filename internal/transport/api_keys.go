@@ -0,0 +1,88 @@
+package transport
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	app "github.com/maxik12233/task-junior"
+	"github.com/maxik12233/task-junior/pkg/api"
+	"github.com/maxik12233/task-junior/pkg/api/render"
+	"go.uber.org/zap"
+)
+
+func (t *Transport) CreateAPIKey(c *gin.Context) {
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		t.log(c).Error("Error given bad json body", zap.Error(err))
+		api.AbortWithError(c, app.WrapE(app.ErrBadRequest, "Bad JSON body"))
+		return
+	}
+
+	if !t.validateRequest(c, req) {
+		return
+	}
+
+	key, plain, err := t.svc.CreateAPIKey(c.Request.Context(), req.ToDomain())
+	if err != nil {
+		api.AbortWithError(c, err)
+		return
+	}
+
+	render.Render(c, http.StatusCreated, NewAPIKeyResponse(key, plain))
+}
+
+func (t *Transport) GetAPIKeys(c *gin.Context) {
+	keys, err := t.svc.GetAPIKeys(c.Request.Context())
+	if err != nil {
+		api.AbortWithError(c, err)
+		return
+	}
+
+	response := GetAPIKeysResponse{
+		Keys: make([]APIKeyResponse, len(keys)),
+	}
+	for i, v := range keys {
+		response.Keys[i] = NewAPIKeyResponse(v, "")
+	}
+
+	render.Render(c, http.StatusOK, response)
+}
+
+func (t *Transport) RotateAPIKey(c *gin.Context) {
+	id, ok := apiKeyId(c)
+	if !ok {
+		return
+	}
+
+	key, plain, err := t.svc.RotateAPIKey(c.Request.Context(), id)
+	if err != nil {
+		api.AbortWithError(c, err)
+		return
+	}
+
+	render.Render(c, http.StatusOK, NewAPIKeyResponse(key, plain))
+}
+
+func (t *Transport) RevokeAPIKey(c *gin.Context) {
+	id, ok := apiKeyId(c)
+	if !ok {
+		return
+	}
+
+	if err := t.svc.RevokeAPIKey(c.Request.Context(), id); err != nil {
+		api.AbortWithError(c, err)
+		return
+	}
+
+	render.Render(c, http.StatusOK, "API key was revoked")
+}
+
+func apiKeyId(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		api.AbortWithError(c, app.WrapE(app.ErrInvalidParamType, "bad api key id"))
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,117 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	app "github.com/maxik12233/task-junior"
+	"github.com/maxik12233/task-junior/internal/domain"
+)
+
+func TestAPIKeys(t *testing.T) {
+	const plain = "tj_0123456789abcdefghijklmnopqrstuvwxyzABCDEFG"
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	key := &domain.APIKey{ID: 4, Name: "batch", Prefix: plain[:11], Scopes: []string{"viewer"}, CreatedAt: created}
+
+	svc := &fakeService{
+		createAPIKey: func(ctx context.Context, req domain.APIKey) (*domain.APIKey, string, error) {
+			if req.Name != "batch" || len(req.Scopes) != 1 || req.Scopes[0] != "viewer" {
+				t.Errorf("created key = %+v", req)
+			}
+			return key, plain, nil
+		},
+		getAPIKeys: func(ctx context.Context) ([]*domain.APIKey, error) {
+			return []*domain.APIKey{key, {ID: 5, Name: "no scopes", CreatedAt: created}}, nil
+		},
+		rotateAPIKey: func(ctx context.Context, id uint) (*domain.APIKey, string, error) {
+			if id != 4 {
+				return nil, "", app.ErrNotFound
+			}
+			return key, plain, nil
+		},
+		revokeAPIKey: func(ctx context.Context, id uint) error {
+			if id != 4 {
+				return app.ErrNotFound
+			}
+			return nil
+		},
+	}
+	router := newTestRouter(t, svc, Options{})
+
+	tests := []struct {
+		name       string
+		method     string
+		url        string
+		body       string
+		wantStatus int
+		// wantKeys are the keys of the response, wantPlain tells if they are shown
+		wantKeys  int
+		wantPlain bool
+	}{
+		{name: "create", method: http.MethodPost, url: "/admin/api-keys", body: `{"name":"batch","scopes":["viewer"]}`, wantStatus: http.StatusCreated, wantKeys: 1, wantPlain: true},
+		{name: "create with tenant switch", method: http.MethodPost, url: "/admin/api-keys", body: `{"name":"batch","scopes":["tenant:switch"]}`, wantStatus: http.StatusBadRequest},
+		{name: "create expired", method: http.MethodPost, url: "/admin/api-keys", body: `{"name":"batch","expires_at":"2000-01-01T00:00:00Z"}`, wantStatus: http.StatusBadRequest},
+		{name: "create without name", method: http.MethodPost, url: "/admin/api-keys", body: `{"scopes":["viewer"]}`, wantStatus: http.StatusBadRequest},
+		{name: "list", method: http.MethodGet, url: "/admin/api-keys", wantStatus: http.StatusOK, wantKeys: 2},
+		{name: "rotate", method: http.MethodPost, url: "/admin/api-keys/4/rotate", wantStatus: http.StatusOK, wantKeys: 1, wantPlain: true},
+		{name: "rotate unknown", method: http.MethodPost, url: "/admin/api-keys/9/rotate", wantStatus: http.StatusNotFound},
+		{name: "rotate bad id", method: http.MethodPost, url: "/admin/api-keys/0/rotate", wantStatus: http.StatusBadRequest},
+		{name: "revoke", method: http.MethodDelete, url: "/admin/api-keys/4", wantStatus: http.StatusOK},
+		{name: "revoke unknown", method: http.MethodDelete, url: "/admin/api-keys/9", wantStatus: http.StatusNotFound},
+		{name: "revoke bad id", method: http.MethodDelete, url: "/admin/api-keys/four", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := serve(router, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if w.Code >= http.StatusBadRequest {
+				decodeProblem(t, w)
+				return
+			}
+			if tt.wantKeys == 0 {
+				return
+			}
+
+			var keys []APIKeyResponse
+			if tt.wantKeys == 1 {
+				var got APIKeyResponse
+				if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+					t.Fatalf("bad body %s: %v", w.Body, err)
+				}
+				keys = []APIKeyResponse{got}
+			} else {
+				var got GetAPIKeysResponse
+				if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+					t.Fatalf("bad body %s: %v", w.Body, err)
+				}
+				keys = got.Keys
+			}
+			if len(keys) != tt.wantKeys {
+				t.Fatalf("got %d keys, want %d", len(keys), tt.wantKeys)
+			}
+
+			// The key is shown once, on creation and rotation
+			if shown := keys[0].Key == plain; shown != tt.wantPlain {
+				t.Errorf("key shown = %v, want %v", shown, tt.wantPlain)
+			}
+			if keys[0].Id != 4 || keys[0].Prefix != key.Prefix || !keys[0].CreatedAt.Equal(created) {
+				t.Errorf("key = %+v, want %+v", keys[0], key)
+			}
+			for _, v := range keys {
+				if v.Scopes == nil {
+					t.Errorf("scopes of key %d are null, want a list", v.Id)
+				}
+			}
+		})
+	}
+}
@@ -1,20 +1,30 @@
 package transport
 
-import "github.com/maxik12233/task-junior/internal/domain"
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/maxik12233/task-junior/internal/domain"
+	"github.com/maxik12233/task-junior/internal/importer"
+	"github.com/maxik12233/task-junior/internal/service"
+	"github.com/maxik12233/task-junior/pkg/countries"
+)
 
 type AddPersonInfoRequest struct {
-	Name       string `json:"name" validate:"required"`
-	Surname    string `json:"surname" validate:"required"`
-	Patronymic string `json:"patronymic"`
+	Name       string `json:"name" validate:"required,person_name"`
+	Surname    string `json:"surname" validate:"required,person_name"`
+	Patronymic string `json:"patronymic" validate:"omitempty,person_name"`
 }
 
 type AddPersonInfoResponse struct {
-	Name        string `json:"name"`
-	Surname     string `json:"surname"`
-	Patronymic  string `json:"patronymic,omitempty"`
-	Gender      string `json:"gender"`
-	Age         int    `json:"age"`
-	Nationality string `json:"nationality"`
+	Name        string `json:"name" xml:"name"`
+	Surname     string `json:"surname" xml:"surname"`
+	Patronymic  string `json:"patronymic,omitempty" xml:"patronymic,omitempty"`
+	Gender      string `json:"gender" xml:"gender"`
+	Age         int    `json:"age" xml:"age"`
+	Nationality string `json:"nationality" xml:"nationality"`
+	DuplicateOf []uint `json:"duplicate_of,omitempty" xml:"duplicate_of>id,omitempty"`
 }
 
 type DeletePersonInfoRequest struct {
@@ -23,28 +33,73 @@ type DeletePersonInfoRequest struct {
 
 type UpdatePersonInfoRequest struct {
 	Id          uint   `json:"id" validate:"required,gte=1"`
-	Name        string `json:"name" validate:"required"`
-	Surname     string `json:"surname" validate:"required"`
-	Patronymic  string `json:"patronymic,omitempty"`
-	Gender      string `json:"gender" validate:"required"`
+	Name        string `json:"name" validate:"required,person_name"`
+	Surname     string `json:"surname" validate:"required,person_name"`
+	Patronymic  string `json:"patronymic,omitempty" validate:"omitempty,person_name"`
+	Gender      string `json:"gender" validate:"required,gender"`
 	Age         int    `json:"age" validate:"required,gte=0,lte=200"`
-	Nationality string `json:"nationality" validate:"required"`
+	Nationality string `json:"nationality" validate:"required,iso3166_alpha2"`
+}
+
+type MergePersonInfoRequest struct {
+	SourceId uint `json:"source_id" validate:"required,gte=1,nefield=TargetId"`
+	TargetId uint `json:"target_id" validate:"required,gte=1"`
 }
 
 type PersonResponse struct {
-	Id          uint   `json:"id,omitempty"`
-	Name        string `json:"name,omitempty"`
-	Surname     string `json:"surname,omitempty"`
-	Patronymic  string `json:"patronymic,omitempty"`
-	Gender      string `json:"gender,omitempty"`
-	Age         int    `json:"age,omitempty"`
-	Nationality string `json:"nationality,omitempty"`
+	Id          uint   `json:"id,omitempty" xml:"id,omitempty"`
+	Name        string `json:"name,omitempty" xml:"name,omitempty"`
+	Surname     string `json:"surname,omitempty" xml:"surname,omitempty"`
+	Patronymic  string `json:"patronymic,omitempty" xml:"patronymic,omitempty"`
+	Gender      string `json:"gender,omitempty" xml:"gender,omitempty"`
+	Age         int    `json:"age,omitempty" xml:"age,omitempty"`
+	Nationality string `json:"nationality,omitempty" xml:"nationality,omitempty"`
+
+	Country *CountryResponse `json:"country,omitempty" xml:"country,omitempty"`
+
+	NameNormalized       string `json:"name_normalized,omitempty" xml:"name_normalized,omitempty"`
+	SurnameNormalized    string `json:"surname_normalized,omitempty" xml:"surname_normalized,omitempty"`
+	PatronymicNormalized string `json:"patronymic_normalized,omitempty" xml:"patronymic_normalized,omitempty"`
 }
 
 type GetPersonInfoResponse struct {
 	PersonResponse
-	TotalCount *int             `json:"total,omitempty"`
-	Persons    []PersonResponse `json:"persons,omitempty"`
+	TotalCount *int             `json:"total,omitempty" xml:"total,omitempty"`
+	Persons    []PersonResponse `json:"persons,omitempty" xml:"persons>person,omitempty"`
+}
+
+type SearchPersonResponse struct {
+	PersonResponse
+	Score     float64 `json:"score" xml:"score"`
+	Highlight string  `json:"highlight" xml:"highlight"`
+}
+
+type SearchPersonInfoResponse struct {
+	TotalCount int                    `json:"total" xml:"total"`
+	Persons    []SearchPersonResponse `json:"persons" xml:"persons>person"`
+}
+
+// NewPersonResponse builds the person response with the country name in the locale.
+func NewPersonResponse(person *domain.Person, locale string) PersonResponse {
+	var country *CountryResponse
+	if v, ok := countries.Lookup(person.Characteristic.Nationality); ok {
+		response := NewCountryResponse(v, locale)
+		country = &response
+	}
+
+	return PersonResponse{
+		Id:                   person.ID,
+		Name:                 person.Name,
+		Surname:              person.Surname,
+		Patronymic:           person.Patronymic,
+		Age:                  person.Characteristic.Age,
+		Gender:               person.Characteristic.Gender,
+		Nationality:          person.Characteristic.Nationality,
+		NameNormalized:       person.NameNormalized,
+		SurnameNormalized:    person.SurnameNormalized,
+		PatronymicNormalized: person.PatronymicNormalized,
+		Country:              country,
+	}
 }
 
 func (person *DeletePersonInfoRequest) ToDomain() domain.Person {
@@ -73,3 +128,206 @@ func (person *AddPersonInfoRequest) ToDomain() domain.Person {
 		Patronymic: person.Patronymic,
 	}
 }
+
+type ImportPersonInfoResponse struct {
+	ReportId  string `json:"report_id" xml:"report_id"`
+	Total     int    `json:"total" xml:"total"`
+	Imported  int    `json:"imported" xml:"imported"`
+	Rejected  int    `json:"rejected" xml:"rejected"`
+	ReportURL string `json:"report_url,omitempty" xml:"report_url,omitempty"`
+}
+
+func NewImportPersonInfoResponse(report *importer.Report) ImportPersonInfoResponse {
+	resp := ImportPersonInfoResponse{
+		ReportId: report.ID,
+		Total:    report.Total,
+		Imported: report.Imported,
+		Rejected: len(report.Rejected),
+	}
+	if len(report.Rejected) > 0 {
+		resp.ReportURL = fmt.Sprintf("/%s/%s/%s/report", entityURL, importURL, report.ID)
+	}
+	return resp
+}
+
+// MarshalCSV renders the list of persons, or the single person if it is not a list.
+func (r GetPersonInfoResponse) MarshalCSV() ([]string, [][]string) {
+	header := []string{"id", "name", "surname", "patronymic", "gender", "age", "nationality"}
+
+	persons := r.Persons
+	if persons == nil {
+		persons = []PersonResponse{r.PersonResponse}
+	}
+
+	rows := make([][]string, len(persons))
+	for i, v := range persons {
+		rows[i] = []string{
+			strconv.Itoa(int(v.Id)),
+			v.Name,
+			v.Surname,
+			v.Patronymic,
+			v.Gender,
+			strconv.Itoa(v.Age),
+			v.Nationality,
+		}
+	}
+
+	return header, rows
+}
+
+func (r SearchPersonInfoResponse) MarshalCSV() ([]string, [][]string) {
+	header := []string{"id", "name", "surname", "patronymic", "gender", "age", "nationality", "score", "highlight"}
+
+	rows := make([][]string, len(r.Persons))
+	for i, v := range r.Persons {
+		rows[i] = []string{
+			strconv.Itoa(int(v.Id)),
+			v.Name,
+			v.Surname,
+			v.Patronymic,
+			v.Gender,
+			strconv.Itoa(v.Age),
+			v.Nationality,
+			strconv.FormatFloat(v.Score, 'f', -1, 64),
+			v.Highlight,
+		}
+	}
+
+	return header, rows
+}
+
+type CountryResponse struct {
+	Code      string `json:"code" xml:"code"`
+	Alpha3    string `json:"alpha3,omitempty" xml:"alpha3,omitempty"`
+	Numeric   string `json:"numeric,omitempty" xml:"numeric,omitempty"`
+	Name      string `json:"name" xml:"name"`
+	Region    string `json:"region" xml:"region"`
+	Continent string `json:"continent" xml:"continent"`
+}
+
+type GetCountriesResponse struct {
+	Countries []CountryResponse `json:"countries" xml:"countries>country"`
+}
+
+func NewCountryResponse(country countries.Country, locale string) CountryResponse {
+	return CountryResponse{
+		Code:      country.Alpha2,
+		Alpha3:    country.Alpha3,
+		Numeric:   country.Numeric,
+		Name:      country.LocalizedName(locale),
+		Region:    country.Region,
+		Continent: country.Continent,
+	}
+}
+
+func (r GetCountriesResponse) MarshalCSV() ([]string, [][]string) {
+	header := []string{"code", "alpha3", "numeric", "name", "region", "continent"}
+
+	rows := make([][]string, len(r.Countries))
+	for i, v := range r.Countries {
+		rows[i] = []string{v.Code, v.Alpha3, v.Numeric, v.Name, v.Region, v.Continent}
+	}
+
+	return header, rows
+}
+
+type PersonStatResponse struct {
+	Key   string `json:"key" xml:"key"`
+	Name  string `json:"name,omitempty" xml:"name,omitempty"`
+	Count int    `json:"count" xml:"count"`
+}
+
+type GetPersonStatsResponse struct {
+	GroupBy string               `json:"group_by" xml:"group_by"`
+	Total   int                  `json:"total" xml:"total"`
+	Groups  []PersonStatResponse `json:"groups" xml:"groups>group"`
+}
+
+// NewGetPersonStatsResponse names nationality groups by the country name in the locale.
+func NewGetPersonStatsResponse(stats service.PersonStats, locale string) GetPersonStatsResponse {
+	response := GetPersonStatsResponse{
+		GroupBy: stats.GroupBy,
+		Groups:  make([]PersonStatResponse, len(stats.Groups)),
+	}
+
+	for i, v := range stats.Groups {
+		group := PersonStatResponse{Key: v.Key, Count: v.Count}
+		if country, ok := countries.Lookup(v.Key); ok && stats.GroupBy == service.GroupByNationality {
+			group.Name = country.LocalizedName(locale)
+		}
+
+		response.Groups[i] = group
+		response.Total += v.Count
+	}
+
+	return response
+}
+
+func (r GetPersonStatsResponse) MarshalCSV() ([]string, [][]string) {
+	header := []string{r.GroupBy, "name", "count"}
+
+	rows := make([][]string, len(r.Groups))
+	for i, v := range r.Groups {
+		rows[i] = []string{v.Key, v.Name, strconv.Itoa(v.Count)}
+	}
+
+	return header, rows
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" validate:"required,max=255"`
+	Scopes    []string   `json:"scopes" validate:"dive,scope"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" validate:"omitempty,gt"`
+}
+
+type APIKeyResponse struct {
+	Id         uint       `json:"id" xml:"id"`
+	Name       string     `json:"name" xml:"name"`
+	Prefix     string     `json:"prefix" xml:"prefix"`
+	Scopes     []string   `json:"scopes" xml:"scopes>scope"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty" xml:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" xml:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" xml:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at" xml:"created_at"`
+	// Key is only returned on creation and rotation.
+	Key string `json:"key,omitempty" xml:"key,omitempty"`
+}
+
+type GetAPIKeysResponse struct {
+	Keys []APIKeyResponse `json:"keys" xml:"keys>key"`
+}
+
+type LogLevelRequest struct {
+	Level string `json:"level" validate:"required,oneof=debug info warn error"`
+}
+
+type LogLevelResponse struct {
+	Level string `json:"level" xml:"level"`
+}
+
+func (r *CreateAPIKeyRequest) ToDomain() domain.APIKey {
+	return domain.APIKey{
+		Name:      r.Name,
+		Scopes:    r.Scopes,
+		ExpiresAt: r.ExpiresAt,
+	}
+}
+
+func NewAPIKeyResponse(key *domain.APIKey, plain string) APIKeyResponse {
+	scopes := key.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	return APIKeyResponse{
+		Id:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     scopes,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		RevokedAt:  key.RevokedAt,
+		CreatedAt:  key.CreatedAt,
+		Key:        plain,
+	}
+}
@@ -0,0 +1,100 @@
+package transport
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	app "github.com/maxik12233/task-junior"
+	"github.com/maxik12233/task-junior/internal/domain"
+	"github.com/maxik12233/task-junior/internal/exporter"
+	"github.com/maxik12233/task-junior/pkg/api"
+	"github.com/maxik12233/task-junior/pkg/api/sort"
+	"go.uber.org/zap"
+)
+
+// exportFlushEvery is how many rows are written between flushes of the response.
+const exportFlushEvery = 500
+
+func (t *Transport) ExportPersonInfo(c *gin.Context) {
+	format, err := exporter.ParseFormat(c.Query("format"))
+	if err != nil {
+		api.AbortWithError(c, err)
+		return
+	}
+
+	var sortOptions *sort.Options
+	if options, ok := c.Request.Context().Value(sort.OptionsContextKey).(sort.Options); ok {
+		sortOptions = &options
+	}
+
+	filter := personFilter(c)
+	if err := filter.Validate(); err != nil {
+		api.AbortWithError(c, err)
+		return
+	}
+
+	// The status and the headers are set with the first row and sent when the
+	// writer flushes it, so the errors before that, e.g. timeouts, are still
+	// responded with a problem.
+	var writer exporter.Writer
+	start := func() error {
+		w, err := exporter.NewWriter(c.Writer, format)
+		if err != nil {
+			t.log(c).Error("Error creating export writer", zap.Error(err))
+			return app.ErrInternal
+		}
+
+		c.Header("Content-Type", format.ContentType())
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="persons.%s"`, format))
+		c.Status(http.StatusOK)
+		writer = w
+		return nil
+	}
+	defer func() {
+		// Releases the temporary files of the xlsx writer if the export fails
+		if writer != nil {
+			writer.Abort()
+		}
+	}()
+
+	written := 0
+	err = t.svc.ExportPersonInfo(c.Request.Context(), sortOptions, filter, func(person *domain.Person) error {
+		if writer == nil {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		if err := writer.Write(person); err != nil {
+			return err
+		}
+
+		written++
+		if written%exportFlushEvery == 0 {
+			c.Writer.Flush()
+		}
+		return nil
+	})
+	if err != nil && !c.Writer.Written() {
+		c.Writer.Header().Del("Content-Disposition")
+		api.AbortWithError(c, err)
+		return
+	}
+	if err != nil {
+		// Headers are already sent, so the client can only notice a truncated body.
+		t.log(c).Error("Error while exporting persons", zap.Error(err), zap.Int("written", written))
+		return
+	}
+
+	// No persons match, the file has the header only
+	if writer == nil {
+		if err := start(); err != nil {
+			api.AbortWithError(c, err)
+			return
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		t.log(c).Error("Error finishing export", zap.Error(err))
+	}
+}
@@ -0,0 +1,117 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	app "github.com/maxik12233/task-junior"
+	"github.com/maxik12233/task-junior/internal/domain"
+	"github.com/maxik12233/task-junior/internal/service"
+)
+
+func TestExportPersonInfo(t *testing.T) {
+	ivan := &domain.Person{ID: 1, Name: "Ivan", Surname: "Ivanov", Characteristic: domain.Characteristic{Age: 30, Gender: "male", Nationality: "RU"}}
+	// Enough rows to fill the buffer of the writer, so they are sent before the failure
+	many := make([]*domain.Person, 500)
+	for i := range many {
+		many[i] = ivan
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		persons    []*domain.Person
+		err        error
+		wantStatus int
+		wantBody   string
+		wantCalled bool
+		// The body is cut at the failure, wantBody is its prefix
+		wantTruncated bool
+	}{
+		{
+			name:       "persons",
+			query:      "?continent=europe",
+			persons:    []*domain.Person{ivan},
+			wantStatus: http.StatusOK,
+			wantBody:   "id,name,surname,patronymic,gender,age,nationality\n1,Ivan,Ivanov,,male,30,RU\n",
+			wantCalled: true,
+		},
+		{
+			name:       "no persons",
+			wantStatus: http.StatusOK,
+			wantBody:   "id,name,surname,patronymic,gender,age,nationality\n",
+			wantCalled: true,
+		},
+		{
+			name:       "unknown region",
+			query:      "?region=atlantis",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "timeout before the first row",
+			err:        app.ErrTimeout,
+			wantStatus: http.StatusGatewayTimeout,
+			wantCalled: true,
+		},
+		{
+			name:       "failure before the rows are sent",
+			persons:    []*domain.Person{ivan},
+			err:        app.ErrTimeout,
+			wantStatus: http.StatusGatewayTimeout,
+			wantCalled: true,
+		},
+		{
+			name:          "failure after the rows are sent",
+			persons:       many,
+			err:           app.ErrTimeout,
+			wantStatus:    http.StatusOK,
+			wantBody:      "id,name,surname,patronymic,gender,age,nationality\n1,Ivan,Ivanov,,male,30,RU\n",
+			wantCalled:    true,
+			wantTruncated: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			svc := &fakeService{
+				exportPersonInfo: func(ctx context.Context, filter service.PersonFilter, fn func(person *domain.Person) error) error {
+					called = true
+					for _, v := range tt.persons {
+						if err := fn(v); err != nil {
+							return err
+						}
+					}
+					return tt.err
+				},
+			}
+
+			w := serve(newTestRouter(t, svc, Options{}), httptest.NewRequest(http.MethodGet, "/person/export"+tt.query, nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if called != tt.wantCalled {
+				t.Errorf("service called = %v, want %v", called, tt.wantCalled)
+			}
+
+			if tt.wantStatus != http.StatusOK {
+				decodeProblem(t, w)
+				if got := w.Header().Get("Content-Disposition"); got != "" {
+					t.Errorf("Content-Disposition = %q, want none", got)
+				}
+				return
+			}
+			if got := w.Header().Get("Content-Type"); got != "text/csv" {
+				t.Errorf("Content-Type = %q, want text/csv", got)
+			}
+			if got := w.Body.String(); tt.wantTruncated && (!strings.HasPrefix(got, tt.wantBody) || strings.Count(got, "\n") > len(tt.persons)) {
+				t.Errorf("body of %d lines, want fewer than %d starting with %q", strings.Count(got, "\n"), len(tt.persons)+1, tt.wantBody)
+			} else if !tt.wantTruncated && got != tt.wantBody {
+				t.Errorf("body = %q, want %q", got, tt.wantBody)
+			}
+		})
+	}
+}
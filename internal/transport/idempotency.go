@@ -0,0 +1,144 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	app "github.com/maxik12233/task-junior"
+	"github.com/maxik12233/task-junior/pkg/api"
+	"go.uber.org/zap"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	idempotencyKeyMaxLength  = 255
+)
+
+// idempotentWriter keeps a copy of the response to store it for the replays.
+type idempotentWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *idempotentWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *idempotentWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// idempotent makes the request with the Idempotency-Key header run once. Retries
+// get the stored response of the first request. Responses with 5xx codes are not
+// stored, so such requests may be retried with the same key.
+func (t *Transport) idempotent(c *gin.Context) {
+	key := c.GetHeader(IdempotencyKeyHeader)
+	if key == "" {
+		c.Next()
+		return
+	}
+	if len(key) > idempotencyKeyMaxLength {
+		api.AbortWithError(c, app.WrapE(app.ErrBadRequest, fmt.Sprintf("idempotency key is longer than %d", idempotencyKeyMaxLength)))
+		return
+	}
+
+	hash, err := requestHash(c)
+	if err != nil {
+		t.log(c).Error("Error reading idempotent request", zap.Error(err))
+		api.AbortWithError(c, app.WrapE(app.ErrBadRequest, "Bad request body"))
+		return
+	}
+
+	stored, err := t.svc.BeginIdempotentRequest(c.Request.Context(), key, hash)
+	if err != nil {
+		api.AbortWithError(c, err)
+		return
+	}
+	if stored != nil {
+		c.Header(IdempotentReplayedHeader, "true")
+		c.Data(stored.StatusCode, stored.ContentType, stored.Response)
+		c.Abort()
+		return
+	}
+
+	writer := &idempotentWriter{ResponseWriter: c.Writer}
+	c.Writer = writer
+
+	c.Next()
+
+	// The response is stored even if the client has gone away, unless the request
+	// was stopped by it, so the retry is handled again
+	ctx := context.WithoutCancel(c.Request.Context())
+	if status := writer.Status(); status >= http.StatusInternalServerError || status == app.StatusClientClosedRequest {
+		err = t.svc.CancelIdempotentRequest(ctx, key)
+	} else {
+		err = t.svc.CompleteIdempotentRequest(ctx, key, status, writer.Header().Get("Content-Type"), writer.body.Bytes())
+	}
+	if err != nil {
+		t.log(c).Error("Error finishing idempotent request", zap.String("key", key), zap.Error(err))
+	}
+}
+
+// requestHash identifies the route and the payload of the request. Multipart
+// forms are hashed by their fields and files, as the boundary may change between retries.
+func requestHash(c *gin.Context) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", c.Request.Method, c.FullPath())
+
+	if c.ContentType() != binding.MIMEMultipartPOSTForm {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return "", err
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		h.Write(body)
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		return "", err
+	}
+
+	fields := make([]string, 0, len(form.Value))
+	for k := range form.Value {
+		fields = append(fields, k)
+	}
+	sort.Strings(fields)
+	for _, k := range fields {
+		fmt.Fprintf(h, "%q=%q\n", k, form.Value[k])
+	}
+
+	files := make([]string, 0, len(form.File))
+	for k := range form.File {
+		files = append(files, k)
+	}
+	sort.Strings(files)
+	for _, k := range files {
+		for _, header := range form.File[k] {
+			fmt.Fprintf(h, "%q=%q %d\n", k, header.Filename, header.Size)
+			f, err := header.Open()
+			if err != nil {
+				return "", err
+			}
+			_, err = io.Copy(h, f)
+			f.Close()
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
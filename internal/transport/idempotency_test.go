@@ -0,0 +1,133 @@
+package transport
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type hashedRequest struct {
+	method      string
+	path        string
+	contentType string
+	body        []byte
+}
+
+// hashRequest returns the hash of the request and its body as read by the handler after hashing.
+func hashRequest(t *testing.T, r hashedRequest) (string, []byte) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	var (
+		hash string
+		body []byte
+	)
+	router := gin.New()
+	handler := func(c *gin.Context) {
+		var err error
+		if hash, err = requestHash(c); err != nil {
+			t.Fatalf("requestHash() error = %v", err)
+		}
+		if c.ContentType() != gin.MIMEMultipartPOSTForm {
+			body, _ = io.ReadAll(c.Request.Body)
+		}
+	}
+	router.POST("/person", handler)
+	router.POST("/person/import", handler)
+
+	req := httptest.NewRequest(r.method, r.path, bytes.NewReader(r.body))
+	req.Header.Set("Content-Type", r.contentType)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	return hash, body
+}
+
+func multipartBody(t *testing.T, boundary string, fields map[string]string, file string) (string, []byte) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	if err := w.SetBoundary(boundary); err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range fields {
+		w.WriteField(k, v)
+	}
+	part, _ := w.CreateFormFile("file", "persons.csv")
+	part.Write([]byte(file))
+	w.Close()
+
+	return w.FormDataContentType(), buf.Bytes()
+}
+
+func TestRequestHash(t *testing.T) {
+	jsonRequest := func(path, body string) hashedRequest {
+		return hashedRequest{method: http.MethodPost, path: path, contentType: gin.MIMEJSON, body: []byte(body)}
+	}
+	multipartRequest := func(boundary, format, file string) hashedRequest {
+		contentType, body := multipartBody(t, boundary, map[string]string{"format": format}, file)
+		return hashedRequest{method: http.MethodPost, path: "/person/import", contentType: contentType, body: body}
+	}
+
+	tests := []struct {
+		name  string
+		a, b  hashedRequest
+		equal bool
+	}{
+		{
+			name:  "same body",
+			a:     jsonRequest("/person", `{"name":"Ivan"}`),
+			b:     jsonRequest("/person", `{"name":"Ivan"}`),
+			equal: true,
+		},
+		{
+			name: "another body",
+			a:    jsonRequest("/person", `{"name":"Ivan"}`),
+			b:    jsonRequest("/person", `{"name":"Petr"}`),
+		},
+		{
+			name: "another route",
+			a:    jsonRequest("/person", `{"name":"Ivan"}`),
+			b:    jsonRequest("/person/import", `{"name":"Ivan"}`),
+		},
+		{
+			name:  "multipart with another boundary",
+			a:     multipartRequest("boundary-a", "csv", "name\nIvan\n"),
+			b:     multipartRequest("boundary-b", "csv", "name\nIvan\n"),
+			equal: true,
+		},
+		{
+			name: "multipart with another file",
+			a:    multipartRequest("boundary-a", "csv", "name\nIvan\n"),
+			b:    multipartRequest("boundary-a", "csv", "name\nPetr\n"),
+		},
+		{
+			name: "multipart with another field",
+			a:    multipartRequest("boundary-a", "csv", "name\nIvan\n"),
+			b:    multipartRequest("boundary-a", "ndjson", "name\nIvan\n"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, _ := hashRequest(t, tt.a)
+			b, _ := hashRequest(t, tt.b)
+			if (a == b) != tt.equal {
+				t.Errorf("hashes equal = %v, want %v", a == b, tt.equal)
+			}
+		})
+	}
+}
+
+func TestRequestHashKeepsBody(t *testing.T) {
+	want := `{"name":"Ivan"}`
+	_, body := hashRequest(t, hashedRequest{method: http.MethodPost, path: "/person", contentType: gin.MIMEJSON, body: []byte(want)})
+	if string(body) != want {
+		t.Errorf("body after hashing = %q, want %q", body, want)
+	}
+}
@@ -0,0 +1,152 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	app "github.com/maxik12233/task-junior"
+	"github.com/maxik12233/task-junior/internal/domain"
+	"github.com/maxik12233/task-junior/internal/importer"
+	"github.com/maxik12233/task-junior/internal/service"
+	"github.com/maxik12233/task-junior/pkg/api"
+	"github.com/maxik12233/task-junior/pkg/api/render"
+	"github.com/maxik12233/task-junior/pkg/api/validation"
+	"github.com/maxik12233/task-junior/pkg/tenant"
+	"go.uber.org/zap"
+)
+
+// ImportRowToPerson validates imported row with the same rules as AddPersonInfoRequest.
+func ImportRowToPerson(validator *validation.Validator, row importer.Row) (domain.Person, error) {
+	req := AddPersonInfoRequest{
+		Name:       row.Fields[importer.FieldName],
+		Surname:    row.Fields[importer.FieldSurname],
+		Patronymic: row.Fields[importer.FieldPatronymic],
+	}
+
+	if err := validator.Struct(req); err != nil {
+		return domain.Person{}, errors.New(strings.Join(validator.Messages(err), "; "))
+	}
+
+	person := req.ToDomain()
+	if gender := row.Fields[importer.FieldGender]; gender != "" {
+		if err := validator.Var(gender, ruleGender); err != nil {
+			return domain.Person{}, fmt.Errorf("bad gender value %q", gender)
+		}
+		person.Characteristic.Gender = gender
+	}
+	if nationality := strings.ToUpper(row.Fields[importer.FieldNationality]); nationality != "" {
+		if err := validator.Var(nationality, ruleNationality); err != nil {
+			return domain.Person{}, fmt.Errorf("bad nationality value %q", nationality)
+		}
+		person.Characteristic.Nationality = nationality
+	}
+	if age := row.Fields[importer.FieldAge]; age != "" {
+		parsed, err := strconv.Atoi(age)
+		if err != nil || parsed < 0 || parsed > 200 {
+			return domain.Person{}, fmt.Errorf("bad age value %q", age)
+		}
+		person.Characteristic.Age = parsed
+	}
+
+	return person, nil
+}
+
+// ImportRowHandler builds importer.RowHandler which creates valid rows through the service.
+func ImportRowHandler(svc service.IService, validator *validation.Validator) importer.RowHandler {
+	return func(ctx context.Context, row importer.Row) error {
+		person, err := ImportRowToPerson(validator, row)
+		if err != nil {
+			return err
+		}
+
+		if _, err := svc.ImportPersonInfo(ctx, person); err != nil {
+			return err
+		}
+		return nil
+	}
+}
+
+func (t *Transport) ImportPersonInfo(c *gin.Context) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		t.log(c).Error("Error getting import file", zap.Error(err))
+		api.AbortWithError(c, app.WrapE(app.ErrBadRequest, "Multipart form must contain file"))
+		return
+	}
+
+	var format importer.Format
+	if formatInForm := c.PostForm("format"); formatInForm != "" {
+		format, err = importer.ParseFormat(formatInForm)
+	} else {
+		format, err = importer.FormatFromFilename(file.Filename)
+	}
+	if err != nil {
+		api.AbortWithError(c, err)
+		return
+	}
+
+	mapping, err := importer.ParseMapping(c.PostForm("mapping"))
+	if err != nil {
+		api.AbortWithError(c, err)
+		return
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		t.log(c).Error("Error opening import file", zap.Error(err))
+		api.AbortWithError(c, app.ErrInternal)
+		return
+	}
+	defer f.Close()
+
+	reader, err := importer.NewReader(f, format, t.options.ImportMapping.Merge(mapping), t.options.ImportMaxLineSize)
+	if err != nil {
+		api.AbortWithError(c, err)
+		return
+	}
+
+	// The report of the stopped import is kept too, as the rows imported before the error stay stored
+	report, err := importer.Run(c.Request.Context(), reader, ImportRowHandler(t.svc, t.validator))
+	report.Tenant = tenant.ID(c.Request.Context())
+	t.importReports.Save(report)
+
+	if err != nil {
+		t.log(c).Error("Error while importing persons", zap.Error(err), zap.Int("imported", report.Imported))
+		api.AbortWithErrorDetails(c, importError(err), NewImportPersonInfoResponse(report))
+		return
+	}
+
+	render.Render(c, http.StatusOK, NewImportPersonInfoResponse(report))
+}
+
+// importError maps the error which stopped the import.
+func importError(err error) error {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return app.ErrCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return app.ErrTimeout
+	default:
+		return app.WrapE(app.ErrBadRequest, fmt.Sprintf("import stopped: %s", err))
+	}
+}
+
+func (t *Transport) GetImportReport(c *gin.Context) {
+	report, ok := t.importReports.Get(c.Param("id"))
+	if !ok || report.Tenant != tenant.ID(c.Request.Context()) {
+		api.AbortWithError(c, app.ErrNotFound)
+		return
+	}
+
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="import-%s-errors.csv"`, report.ID))
+	c.Status(http.StatusOK)
+	if err := report.WriteCSV(c.Writer); err != nil {
+		t.log(c).Error("Error writing import report", zap.Error(err))
+	}
+}
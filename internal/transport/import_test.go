@@ -0,0 +1,92 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	app "github.com/maxik12233/task-junior"
+	"github.com/maxik12233/task-junior/internal/domain"
+	"github.com/maxik12233/task-junior/internal/service"
+)
+
+func TestImportPersonInfo(t *testing.T) {
+	const file = "name,surname\nIvan,Ivanov\nPetr,Petrov\n1van,Sidorov\nSidor,Sidorov\n"
+
+	tests := []struct {
+		name string
+		// cancelAfter cancels the request after the rows are imported, zero doesn't
+		cancelAfter int
+		wantStatus  int
+		want        ImportPersonInfoResponse
+	}{
+		{
+			name:       "all rows",
+			wantStatus: http.StatusOK,
+			want:       ImportPersonInfoResponse{Total: 4, Imported: 3, Rejected: 1},
+		},
+		{
+			name:        "stopped import",
+			cancelAfter: 2,
+			wantStatus:  app.StatusClientClosedRequest,
+			want:        ImportPersonInfoResponse{Total: 2, Imported: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			imported := 0
+			svc := &fakeService{
+				importPersonInfo: func(ctx context.Context, person domain.Person) (service.CombinedInfo, error) {
+					imported++
+					if imported == tt.cancelAfter {
+						cancel()
+					}
+					return service.CombinedInfo{Name: person.Name}, nil
+				},
+			}
+			router := newTestRouter(t, svc, Options{})
+
+			contentType, body := multipartBody(t, "boundary", nil, file)
+			req := httptest.NewRequest(http.MethodPost, "/person/import", bytes.NewReader(body)).WithContext(ctx)
+			req.Header.Set("Content-Type", contentType)
+			w := serve(router, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.wantStatus, w.Body)
+			}
+
+			var got ImportPersonInfoResponse
+			if tt.wantStatus == http.StatusOK {
+				if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+					t.Fatalf("bad body %s: %v", w.Body, err)
+				}
+			} else {
+				details, _ := json.Marshal(decodeProblem(t, w).Details)
+				if err := json.Unmarshal(details, &got); err != nil {
+					t.Fatalf("bad problem details %s: %v", details, err)
+				}
+			}
+
+			if got.ReportId == "" {
+				t.Fatal("report_id is empty")
+			}
+			reportId := got.ReportId
+			got.ReportId, got.ReportURL = "", ""
+			if got != tt.want {
+				t.Errorf("response = %+v, want %+v", got, tt.want)
+			}
+
+			// The report of the stopped import is kept too
+			w = serve(router, httptest.NewRequest(http.MethodGet, "/person/import/"+reportId+"/report", nil))
+			if w.Code != http.StatusOK {
+				t.Errorf("report status = %d, want %d", w.Code, http.StatusOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package transport
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	app "github.com/maxik12233/task-junior"
+	"github.com/maxik12233/task-junior/pkg/api"
+	"github.com/maxik12233/task-junior/pkg/api/render"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func (t *Transport) GetLogLevel(c *gin.Context) {
+	render.Render(c, http.StatusOK, LogLevelResponse{Level: t.options.LogLevel.String()})
+}
+
+// SetLogLevel changes the level of all loggers of the service until the restart.
+func (t *Transport) SetLogLevel(c *gin.Context) {
+	var req LogLevelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		t.log(c).Error("Error given bad json body", zap.Error(err))
+		api.AbortWithError(c, app.WrapE(app.ErrBadRequest, "Bad JSON body"))
+		return
+	}
+
+	if !t.validateRequest(c, req) {
+		return
+	}
+
+	level, err := zapcore.ParseLevel(req.Level)
+	if err != nil {
+		api.AbortWithError(c, app.WrapE(app.ErrBadRequest, "Bad log level"))
+		return
+	}
+
+	previous := t.options.LogLevel.Level()
+	t.options.LogLevel.SetLevel(level)
+	t.log(c).Warn("Log level is changed",
+		zap.Stringer("from", previous),
+		zap.Stringer("to", level),
+	)
+
+	render.Render(c, http.StatusOK, LogLevelResponse{Level: level.String()})
+}
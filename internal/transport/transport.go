@@ -1,15 +1,24 @@
 package transport
 
 import (
+	"context"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator/v10"
 	app "github.com/maxik12233/task-junior"
+	"github.com/maxik12233/task-junior/internal/importer"
 	"github.com/maxik12233/task-junior/internal/service"
+	"github.com/maxik12233/task-junior/pkg/api"
+	"github.com/maxik12233/task-junior/pkg/api/auth"
 	"github.com/maxik12233/task-junior/pkg/api/paginate"
+	"github.com/maxik12233/task-junior/pkg/api/ratelimit"
+	"github.com/maxik12233/task-junior/pkg/api/render"
 	"github.com/maxik12233/task-junior/pkg/api/sort"
+	"github.com/maxik12233/task-junior/pkg/api/validation"
+	"github.com/maxik12233/task-junior/pkg/countries"
+	"github.com/maxik12233/task-junior/pkg/logger"
+	"github.com/maxik12233/task-junior/pkg/tenant"
 	"go.uber.org/zap"
 )
 
@@ -21,17 +30,50 @@ type ITransport interface {
 
 const (
 	entityURL = "person"
+	importURL = "import"
+	exportURL = "export"
+	mergeURL  = "merge"
+	searchURL = "search"
+	statsURL  = "stats"
+
+	countriesURL = "countries"
+
+	adminURL    = "admin"
+	apiKeysURL  = "api-keys"
+	logLevelURL = "log-level"
+
+	importReportsLimit = 100
 )
 
 type Transport struct {
-	svc    service.IService
-	logger *zap.Logger
+	svc           service.IService
+	logger        *zap.Logger
+	validator     *validation.Validator
+	options       Options
+	importReports *importer.Store
 }
 
-func NewTransport(svc service.IService, logger *zap.Logger) ITransport {
+type Options struct {
+	ImportMapping importer.Mapping
+	// ImportMaxLineSize is the longest ndjson line of the import, zero is importer.DefaultMaxLineSize.
+	ImportMaxLineSize int
+	// Authenticator protects private routes. If nil, private routes are open.
+	Authenticator *auth.Authenticator
+	// TenantPerPage overrides the default page size for the tenants.
+	TenantPerPage map[string]int
+	// RateLimiter limits the request rate of the clients. If nil, requests are not limited.
+	RateLimiter *ratelimit.Limiter
+	// LogLevel is changed by the admin at runtime. If nil, the level routes are not registered.
+	LogLevel *zap.AtomicLevel
+}
+
+func NewTransport(svc service.IService, logger *zap.Logger, validator *validation.Validator, options Options) ITransport {
 	return &Transport{
-		svc:    svc,
-		logger: logger,
+		svc:           svc,
+		logger:        logger,
+		validator:     validator,
+		options:       options,
+		importReports: importer.NewStore(importReportsLimit),
 	}
 }
 
@@ -39,66 +81,157 @@ func (t *Transport) RegisterRoutes(router *gin.Engine) {
 
 	public := router.Group("")
 
-	statisticEntity := public.Group(entityURL)
-	statisticEntity.GET("", t.GetPersonInfo)
-	statisticEntity.POST("", t.AddPersonInfo)
-	statisticEntity.DELETE("", t.DeletePersonInfo)
-	statisticEntity.PUT("", t.UpdatePersonInfo)
+	private := router.Group("")
+	if t.options.RateLimiter != nil {
+		private.Use(t.options.RateLimiter.IPMiddleware())
+	}
+	if t.options.Authenticator != nil {
+		private.Use(t.options.Authenticator.Middleware())
+	}
+	private.Use(tenant.Middleware(t.options.Authenticator != nil), t.tenantPagination)
+	if t.options.RateLimiter != nil {
+		public.Use(t.options.RateLimiter.Middleware())
+		private.Use(t.options.RateLimiter.Middleware())
+	}
+
+	read := t.allow(auth.PermissionPersonRead)
+	write := t.allow(auth.PermissionPersonWrite)
+	remove := t.allow(auth.PermissionPersonDelete)
+
+	statisticEntity := private.Group(entityURL)
+	statisticEntity.GET("", read, t.GetPersonInfo)
+	statisticEntity.POST("", write, t.idempotent, t.AddPersonInfo)
+	statisticEntity.DELETE("", remove, t.DeletePersonInfo)
+	statisticEntity.PUT("", write, t.UpdatePersonInfo)
+	statisticEntity.GET(exportURL, read, t.ExportPersonInfo)
+	statisticEntity.GET(searchURL, read, t.SearchPersonInfo)
+	statisticEntity.POST(mergeURL, remove, t.MergePersonInfo)
+	statisticEntity.POST(importURL, write, t.idempotent, t.ImportPersonInfo)
+	statisticEntity.GET(importURL+"/:id/report", read, t.GetImportReport)
+	statisticEntity.GET(statsURL, read, t.GetPersonStats)
+
+	public.GET(countriesURL, t.GetCountries)
+
+	manageKeys := t.allow(auth.PermissionAPIKeyManage)
+
+	admin := private.Group(adminURL)
+	admin.POST(apiKeysURL, manageKeys, t.CreateAPIKey)
+	admin.GET(apiKeysURL, manageKeys, t.GetAPIKeys)
+	admin.POST(apiKeysURL+"/:id/rotate", manageKeys, t.RotateAPIKey)
+	admin.DELETE(apiKeysURL+"/:id", manageKeys, t.RevokeAPIKey)
+
+	if t.options.LogLevel != nil {
+		manageLog := t.allow(auth.PermissionLogManage)
+		admin.GET(logLevelURL, manageLog, t.GetLogLevel)
+		admin.PUT(logLevelURL, manageLog, t.SetLogLevel)
+	}
+}
+
+// allow declares the permission required by the route. Permissions are not
+// checked when authentication is disabled, as there is no caller to check.
+func (t *Transport) allow(permission auth.Permission) gin.HandlerFunc {
+	if t.options.Authenticator == nil {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+	return auth.RequirePermission(t.logger, permission)
+}
+
+// tenantPagination applies the default page size of the tenant if the request has none.
+func (t *Transport) tenantPagination(c *gin.Context) {
+	perPage, ok := t.options.TenantPerPage[tenant.ID(c.Request.Context())]
+	if ok && c.Query("per_page") == "" {
+		if options, ok := c.Request.Context().Value(paginate.OptionsContextKey).(paginate.Options); ok {
+			options.PerPage = perPage
+			ctx := context.WithValue(c.Request.Context(), paginate.OptionsContextKey, options)
+			c.Request = c.Request.WithContext(ctx)
+		}
+	}
+
+	c.Next()
+}
+
+// log returns the request-scoped logger, or the transport logger.
+func (t *Transport) log(c *gin.Context) *zap.Logger {
+	return logger.FromContext(c.Request.Context(), t.logger)
+}
+
+// locale is the language of localized response fields, like country names.
+func locale(c *gin.Context) string {
+	return api.Locale(c, countries.LocaleEn, countries.LocaleRu)
+}
+
+// personFilter reads the person filter from the region and continent queries.
+func personFilter(c *gin.Context) service.PersonFilter {
+	return service.PersonFilter{
+		Region:    c.Query("region"),
+		Continent: c.Query("continent"),
+	}
+}
+
+// validateRequest validates the request body and responds with per-field
+// error details if it is invalid.
+func (t *Transport) validateRequest(c *gin.Context, req interface{}) bool {
+	err := t.validator.Struct(req)
+	if err == nil {
+		return true
+	}
+
+	t.log(c).Error("Failed struct validation", zap.Error(err))
+	api.AbortWithErrorDetails(c, app.WrapE(app.ErrValidation, "Failed validation"),
+		t.validator.FieldErrors(err, c.GetHeader("Accept-Language")))
+	return false
 }
 
 func (t *Transport) AddPersonInfo(c *gin.Context) {
 	var req AddPersonInfoRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		t.logger.Error("Error given bad json body", zap.Error(err))
-		c.JSON(app.GetHTTPCodeFromError(app.ErrBadRequest), app.WrapE(app.ErrBadRequest, "Bad JSON body").Error())
+		t.log(c).Error("Error given bad json body", zap.Error(err))
+		api.AbortWithError(c, app.WrapE(app.ErrBadRequest, "Bad JSON body"))
 		return
 	}
 
-	validate := validator.New()
-	if err := validate.Struct(req); err != nil {
-		t.logger.Error("Failed struct validation", zap.Error(err))
-		c.JSON(app.GetHTTPCodeFromError(app.ErrBadRequest), app.WrapE(app.ErrBadRequest, "Bad JSON body. Failed validation").Error())
+	if !t.validateRequest(c, req) {
 		return
 	}
 
 	info, err := t.svc.CreatePersonInfo(c.Request.Context(), req.ToDomain())
 	if err != nil {
-		c.JSON(app.GetHTTPCodeFromError(err), err.Error())
+		api.AbortWithError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, AddPersonInfoResponse{
+	render.Render(c, http.StatusOK, AddPersonInfoResponse{
 		Name:        req.Name,
 		Surname:     req.Surname,
 		Patronymic:  req.Patronymic,
 		Age:         info.Age,
 		Gender:      info.Gender,
 		Nationality: info.Nationality,
+		DuplicateOf: info.DuplicateOf,
 	})
 }
 
 func (t *Transport) DeletePersonInfo(c *gin.Context) {
 	var req DeletePersonInfoRequest
-	if err := c.BindJSON(&req); err != nil {
-		t.logger.Error("Error given bad json body", zap.Error(err))
-		c.JSON(app.GetHTTPCodeFromError(app.ErrBadRequest), app.WrapE(app.ErrBadRequest, "Bad JSON body").Error())
+	if err := c.ShouldBindJSON(&req); err != nil {
+		t.log(c).Error("Error given bad json body", zap.Error(err))
+		api.AbortWithError(c, app.WrapE(app.ErrBadRequest, "Bad JSON body"))
 		return
 	}
 
-	validate := validator.New()
-	if err := validate.Struct(req); err != nil {
-		t.logger.Error("Failed struct validation", zap.Error(err))
-		c.JSON(app.GetHTTPCodeFromError(app.ErrBadRequest), app.WrapE(app.ErrBadRequest, "Bad JSON body. Failed validation").Error())
+	if !t.validateRequest(c, req) {
 		return
 	}
 
 	err := t.svc.DeletePersonInfo(c.Request.Context(), int(req.Id))
 	if err != nil {
-		c.JSON(app.GetHTTPCodeFromError(err), err.Error())
+		api.AbortWithError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, "Entity was deleted")
+	render.Render(c, http.StatusOK, "Entity was deleted")
 }
 
 func (t *Transport) GetPersonInfo(c *gin.Context) {
@@ -116,49 +249,33 @@ func (t *Transport) GetPersonInfo(c *gin.Context) {
 	if err == nil {
 		person, err := t.svc.GetPersonInfo(c.Request.Context(), uint(id))
 		if err != nil {
-			c.JSON(app.GetHTTPCodeFromError(err), err.Error())
+			api.AbortWithError(c, err)
 			return
 		}
 
-		c.JSON(http.StatusOK, GetPersonInfoResponse{
-			PersonResponse: PersonResponse{
-				Id:          person.ID,
-				Name:        person.Name,
-				Surname:     person.Surname,
-				Patronymic:  person.Patronymic,
-				Age:         person.Characteristic.Age,
-				Gender:      person.Characteristic.Gender,
-				Nationality: person.Characteristic.Nationality,
-			},
+		render.Render(c, http.StatusOK, GetPersonInfoResponse{
+			PersonResponse: NewPersonResponse(person, locale(c)),
 		})
 	} else {
 
-		persons, err := t.svc.GetAllPersonInfo(c.Request.Context(), sortOptions, paginateOptions)
+		persons, err := t.svc.GetAllPersonInfo(c.Request.Context(), sortOptions, paginateOptions, personFilter(c))
 		if err != nil {
-			c.JSON(app.GetHTTPCodeFromError(err), err.Error())
+			api.AbortWithError(c, err)
 			return
 		}
 
-		count, err := t.svc.GetPersonCount(c.Request.Context())
+		count, err := t.svc.GetPersonCount(c.Request.Context(), personFilter(c))
 		if err != nil {
-			c.JSON(app.GetHTTPCodeFromError(err), err.Error())
+			api.AbortWithError(c, err)
 			return
 		}
 
 		personResponses := make([]PersonResponse, len(persons))
 		for i, v := range persons {
-			personResponses[i] = PersonResponse{
-				Id:          v.ID,
-				Name:        v.Name,
-				Surname:     v.Surname,
-				Patronymic:  v.Patronymic,
-				Age:         v.Characteristic.Age,
-				Gender:      v.Characteristic.Gender,
-				Nationality: v.Characteristic.Nationality,
-			}
+			personResponses[i] = NewPersonResponse(v, locale(c))
 		}
 
-		c.JSON(http.StatusOK, GetPersonInfoResponse{
+		render.Render(c, http.StatusOK, GetPersonInfoResponse{
 			TotalCount: &count,
 			Persons:    personResponses,
 		})
@@ -167,25 +284,103 @@ func (t *Transport) GetPersonInfo(c *gin.Context) {
 
 func (t *Transport) UpdatePersonInfo(c *gin.Context) {
 	var req UpdatePersonInfoRequest
-	if err := c.BindJSON(&req); err != nil {
-		t.logger.Error("Error given bad json body", zap.Error(err))
-		c.JSON(app.GetHTTPCodeFromError(app.ErrBadRequest), app.WrapE(app.ErrBadRequest, "Bad JSON body").Error())
+	if err := c.ShouldBindJSON(&req); err != nil {
+		t.log(c).Error("Error given bad json body", zap.Error(err))
+		api.AbortWithError(c, app.WrapE(app.ErrBadRequest, "Bad JSON body"))
 		return
 	}
 
-	validate := validator.New()
-	if err := validate.Struct(req); err != nil {
-		t.logger.Error("Failed struct validation", zap.Error(err))
-		c.JSON(app.GetHTTPCodeFromError(app.ErrBadRequest), app.WrapE(app.ErrBadRequest, "Bad JSON body. Failed validation").Error())
+	if !t.validateRequest(c, req) {
 		return
 	}
 
 	person, char := req.ToDomain()
 	err := t.svc.UpdatePersonInfo(c.Request.Context(), person, char)
 	if err != nil {
-		c.JSON(app.GetHTTPCodeFromError(err), err.Error())
+		api.AbortWithError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, "Entity was updated")
+	render.Render(c, http.StatusOK, "Entity was updated")
+}
+
+func (t *Transport) MergePersonInfo(c *gin.Context) {
+	var req MergePersonInfoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		t.log(c).Error("Error given bad json body", zap.Error(err))
+		api.AbortWithError(c, app.WrapE(app.ErrBadRequest, "Bad JSON body"))
+		return
+	}
+
+	if !t.validateRequest(c, req) {
+		return
+	}
+
+	person, err := t.svc.MergePersonInfo(c.Request.Context(), req.SourceId, req.TargetId)
+	if err != nil {
+		api.AbortWithError(c, err)
+		return
+	}
+
+	render.Render(c, http.StatusOK, GetPersonInfoResponse{
+		PersonResponse: NewPersonResponse(person, locale(c)),
+	})
+}
+
+func (t *Transport) SearchPersonInfo(c *gin.Context) {
+	var paginateOptions *paginate.Options
+	if options, ok := c.Request.Context().Value(paginate.OptionsContextKey).(paginate.Options); ok {
+		paginateOptions = &options
+	}
+
+	results, count, err := t.svc.SearchPersonInfo(c.Request.Context(), c.Query("q"), paginateOptions)
+	if err != nil {
+		api.AbortWithError(c, err)
+		return
+	}
+
+	personResponses := make([]SearchPersonResponse, len(results))
+	for i, v := range results {
+		personResponses[i] = SearchPersonResponse{
+			PersonResponse: NewPersonResponse(&v.Person, locale(c)),
+			Score:          v.Score,
+			Highlight:      v.Highlight,
+		}
+	}
+
+	render.Render(c, http.StatusOK, SearchPersonInfoResponse{
+		TotalCount: count,
+		Persons:    personResponses,
+	})
+}
+
+func (t *Transport) GetPersonStats(c *gin.Context) {
+	groupBy := c.DefaultQuery("group_by", service.GroupByNationality)
+
+	stats, err := t.svc.GetPersonStats(c.Request.Context(), groupBy, personFilter(c))
+	if err != nil {
+		api.AbortWithError(c, err)
+		return
+	}
+
+	render.Render(c, http.StatusOK, NewGetPersonStatsResponse(stats, locale(c)))
+}
+
+func (t *Transport) GetCountries(c *gin.Context) {
+	region, continent := c.Query("region"), c.Query("continent")
+
+	list := countries.Filter(region, continent)
+	if len(list) == 0 && (region != "" || continent != "") {
+		api.AbortWithError(c, app.WrapE(app.ErrInvalidParamType, "unknown region or continent"))
+		return
+	}
+
+	response := GetCountriesResponse{
+		Countries: make([]CountryResponse, len(list)),
+	}
+	for i, v := range list {
+		response.Countries[i] = NewCountryResponse(v, locale(c))
+	}
+
+	render.Render(c, http.StatusOK, response)
 }
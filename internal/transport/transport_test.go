@@ -0,0 +1,647 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	app "github.com/maxik12233/task-junior"
+	"github.com/maxik12233/task-junior/internal/domain"
+	"github.com/maxik12233/task-junior/internal/service"
+	"github.com/maxik12233/task-junior/pkg/api"
+	"github.com/maxik12233/task-junior/pkg/api/paginate"
+	"github.com/maxik12233/task-junior/pkg/api/sort"
+	"github.com/maxik12233/task-junior/pkg/api/validation"
+	"go.uber.org/zap"
+)
+
+// fakeService implements the service methods used by the handler tests.
+// The methods without a func panic on the nil embedded interface.
+type fakeService struct {
+	service.IService
+
+	createPersonInfo func(ctx context.Context, person domain.Person) (service.CombinedInfo, error)
+	importPersonInfo func(ctx context.Context, person domain.Person) (service.CombinedInfo, error)
+	getAllPersonInfo func(ctx context.Context, filter service.PersonFilter) ([]*domain.Person, error)
+	getPersonInfo    func(ctx context.Context, id uint) (*domain.Person, error)
+	exportPersonInfo func(ctx context.Context, filter service.PersonFilter, fn func(person *domain.Person) error) error
+	mergePersonInfo  func(ctx context.Context, sourceId, targetId uint) (*domain.Person, error)
+	searchPersonInfo func(ctx context.Context, query string) ([]*domain.PersonSearchResult, int, error)
+	getPersonStats   func(ctx context.Context, groupBy string, filter service.PersonFilter) (service.PersonStats, error)
+	createAPIKey     func(ctx context.Context, key domain.APIKey) (*domain.APIKey, string, error)
+	getAPIKeys       func(ctx context.Context) ([]*domain.APIKey, error)
+	rotateAPIKey     func(ctx context.Context, id uint) (*domain.APIKey, string, error)
+	revokeAPIKey     func(ctx context.Context, id uint) error
+}
+
+func (s *fakeService) CreatePersonInfo(ctx context.Context, person domain.Person) (service.CombinedInfo, error) {
+	return s.createPersonInfo(ctx, person)
+}
+
+func (s *fakeService) ImportPersonInfo(ctx context.Context, person domain.Person) (service.CombinedInfo, error) {
+	return s.importPersonInfo(ctx, person)
+}
+
+func (s *fakeService) GetAllPersonInfo(ctx context.Context, sortOption *sort.Options, paginateOption *paginate.Options, filter service.PersonFilter) ([]*domain.Person, error) {
+	return s.getAllPersonInfo(ctx, filter)
+}
+
+func (s *fakeService) GetPersonCount(ctx context.Context, filter service.PersonFilter) (int, error) {
+	persons, err := s.getAllPersonInfo(ctx, filter)
+	return len(persons), err
+}
+
+func (s *fakeService) GetPersonInfo(ctx context.Context, id uint) (*domain.Person, error) {
+	return s.getPersonInfo(ctx, id)
+}
+
+func (s *fakeService) ExportPersonInfo(ctx context.Context, sortOption *sort.Options, filter service.PersonFilter, fn func(person *domain.Person) error) error {
+	return s.exportPersonInfo(ctx, filter, fn)
+}
+
+func (s *fakeService) MergePersonInfo(ctx context.Context, sourceId, targetId uint) (*domain.Person, error) {
+	return s.mergePersonInfo(ctx, sourceId, targetId)
+}
+
+func (s *fakeService) SearchPersonInfo(ctx context.Context, query string, paginateOption *paginate.Options) ([]*domain.PersonSearchResult, int, error) {
+	return s.searchPersonInfo(ctx, query)
+}
+
+func (s *fakeService) GetPersonStats(ctx context.Context, groupBy string, filter service.PersonFilter) (service.PersonStats, error) {
+	return s.getPersonStats(ctx, groupBy, filter)
+}
+
+func (s *fakeService) CreateAPIKey(ctx context.Context, key domain.APIKey) (*domain.APIKey, string, error) {
+	return s.createAPIKey(ctx, key)
+}
+
+func (s *fakeService) GetAPIKeys(ctx context.Context) ([]*domain.APIKey, error) {
+	return s.getAPIKeys(ctx)
+}
+
+func (s *fakeService) RotateAPIKey(ctx context.Context, id uint) (*domain.APIKey, string, error) {
+	return s.rotateAPIKey(ctx, id)
+}
+
+func (s *fakeService) RevokeAPIKey(ctx context.Context, id uint) error {
+	return s.revokeAPIKey(ctx, id)
+}
+
+// newTestRouter serves the routes of the transport over the service without authentication.
+func newTestRouter(t *testing.T, svc service.IService, options Options) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	validator, err := NewValidator()
+	if err != nil {
+		t.Fatalf("NewValidator() error = %v", err)
+	}
+
+	router := gin.New()
+	router.Use(paginate.Middleware(1, 10))
+	NewTransport(svc, zap.NewNop(), validator, options).RegisterRoutes(router)
+	return router
+}
+
+// serve serves the request and returns the recorded response.
+func serve(router *gin.Engine, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// decodeProblem decodes the problem details of the response.
+func decodeProblem(t *testing.T, w *httptest.ResponseRecorder) api.ErrorResponse {
+	t.Helper()
+
+	if got := w.Header().Get("Content-Type"); got != api.ProblemContentType {
+		t.Fatalf("Content-Type = %q, want %q, body %s", got, api.ProblemContentType, w.Body)
+	}
+	var problem api.ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("bad problem body %s: %v", w.Body, err)
+	}
+	return problem
+}
+
+func TestGetPersonInfoFormats(t *testing.T) {
+	ivan := &domain.Person{ID: 1, Name: "Ivan", Surname: "Ivanov", Characteristic: domain.Characteristic{Age: 30, Gender: "male", Nationality: "RU"}}
+	svc := &fakeService{
+		getAllPersonInfo: func(ctx context.Context, filter service.PersonFilter) ([]*domain.Person, error) {
+			return []*domain.Person{ivan}, nil
+		},
+		getPersonInfo: func(ctx context.Context, id uint) (*domain.Person, error) {
+			return ivan, nil
+		},
+	}
+	router := newTestRouter(t, svc, Options{})
+
+	tests := []struct {
+		name            string
+		query           string
+		accept          string
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:            "list as csv",
+			accept:          "text/csv",
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/csv; charset=utf-8",
+			wantBody:        "id,name,surname,patronymic,gender,age,nationality\n1,Ivan,Ivanov,,male,30,RU\n",
+		},
+		{
+			name:            "person as csv",
+			query:           "?id=1",
+			accept:          "text/csv",
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/csv; charset=utf-8",
+			wantBody:        "id,name,surname,patronymic,gender,age,nationality\n1,Ivan,Ivanov,,male,30,RU\n",
+		},
+		{
+			name:            "list as xml",
+			accept:          "application/xml",
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/xml; charset=utf-8",
+		},
+		{
+			name:            "list as msgpack",
+			accept:          "application/msgpack",
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/msgpack; charset=utf-8",
+		},
+		{
+			name:       "unsupported type",
+			accept:     "application/pdf",
+			wantStatus: http.StatusNotAcceptable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/person"+tt.query, nil)
+			req.Header.Set("Accept", tt.accept)
+			w := serve(router, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				decodeProblem(t, w)
+				return
+			}
+
+			if got := w.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantContentType)
+			}
+			if !strings.Contains(w.Body.String(), "Ivanov") {
+				t.Errorf("body %q has no person", w.Body)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", w.Body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestAddPersonInfoDuplicates(t *testing.T) {
+	tests := []struct {
+		name       string
+		info       service.CombinedInfo
+		err        error
+		wantStatus int
+		wantCode   int
+		wantDupOf  []uint
+	}{
+		{
+			name:       "no duplicates",
+			info:       service.CombinedInfo{Age: 30, Gender: "male", Nationality: "RU"},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "warned duplicates",
+			info:       service.CombinedInfo{Age: 30, Gender: "male", Nationality: "RU", DuplicateOf: []uint{3, 7}},
+			wantStatus: http.StatusOK,
+			wantDupOf:  []uint{3, 7},
+		},
+		{
+			name:       "rejected duplicate",
+			err:        app.ErrDuplicate,
+			wantStatus: http.StatusConflict,
+			wantCode:   app.ErrorCode(app.ErrDuplicate),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &fakeService{
+				createPersonInfo: func(ctx context.Context, person domain.Person) (service.CombinedInfo, error) {
+					return tt.info, tt.err
+				},
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/person", strings.NewReader(`{"name":"Ivan","surname":"Ivanov"}`))
+			req.Header.Set("Content-Type", "application/json")
+			w := serve(newTestRouter(t, svc, Options{}), req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if problem := decodeProblem(t, w); problem.Code != tt.wantCode {
+					t.Errorf("code = %d, want %d", problem.Code, tt.wantCode)
+				}
+				return
+			}
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("bad body %s: %v", w.Body, err)
+			}
+			dupOf, ok := got["duplicate_of"].([]interface{})
+			if ok != (tt.wantDupOf != nil) || len(dupOf) != len(tt.wantDupOf) {
+				t.Fatalf("duplicate_of = %v, want %v", got["duplicate_of"], tt.wantDupOf)
+			}
+			for i, v := range tt.wantDupOf {
+				if dupOf[i] != float64(v) {
+					t.Errorf("duplicate_of = %v, want %v", dupOf, tt.wantDupOf)
+				}
+			}
+		})
+	}
+}
+
+func TestMergePersonInfo(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		err        error
+		wantStatus int
+		wantCalled bool
+		wantField  string
+	}{
+		{
+			name:       "merged",
+			body:       `{"source_id":2,"target_id":1}`,
+			wantStatus: http.StatusOK,
+			wantCalled: true,
+		},
+		{
+			name:       "into itself",
+			body:       `{"source_id":1,"target_id":1}`,
+			wantStatus: http.StatusBadRequest,
+			wantField:  "source_id",
+		},
+		{
+			name:       "no target",
+			body:       `{"source_id":2}`,
+			wantStatus: http.StatusBadRequest,
+			wantField:  "target_id",
+		},
+		{
+			name:       "bad json",
+			body:       `{"source_id":"2"`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "unknown person",
+			body:       `{"source_id":2,"target_id":9}`,
+			err:        app.ErrNotFound,
+			wantStatus: http.StatusNotFound,
+			wantCalled: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			svc := &fakeService{
+				mergePersonInfo: func(ctx context.Context, sourceId, targetId uint) (*domain.Person, error) {
+					called = true
+					if tt.err != nil {
+						return nil, tt.err
+					}
+					return &domain.Person{ID: targetId, Name: "Ivan", Surname: "Ivanov"}, nil
+				},
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/person/merge", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := serve(newTestRouter(t, svc, Options{}), req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if called != tt.wantCalled {
+				t.Errorf("service called = %v, want %v", called, tt.wantCalled)
+			}
+			if tt.wantStatus != http.StatusOK {
+				problem := decodeProblem(t, w)
+				if tt.wantField == "" {
+					return
+				}
+				details, _ := json.Marshal(problem.Details)
+				if !strings.Contains(string(details), `"field":"`+tt.wantField+`"`) {
+					t.Errorf("details = %s, want an error of %s", details, tt.wantField)
+				}
+				return
+			}
+
+			var got GetPersonInfoResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("bad body %s: %v", w.Body, err)
+			}
+			if got.Id != 1 || got.Name != "Ivan" {
+				t.Errorf("merged person = %+v, want the target", got.PersonResponse)
+			}
+		})
+	}
+}
+
+func TestSearchPersonInfo(t *testing.T) {
+	results := []*domain.PersonSearchResult{
+		{Person: domain.Person{ID: 2, Name: "Ivan", Surname: "Ivanov"}, Score: 1.5, Highlight: "<mark>Ivan</mark> <mark>Ivanov</mark>"},
+		{Person: domain.Person{ID: 1, Name: "Ivan", Surname: "<b>"}, Score: 0.5, Highlight: "<mark>Ivan</mark> &lt;b&gt;"},
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		err        error
+		wantStatus int
+	}{
+		{name: "results", query: "?q=Ivan+Ivanov", wantStatus: http.StatusOK},
+		{name: "empty query", err: app.ErrNotAllRequiredQueries, wantStatus: http.StatusBadRequest},
+		{name: "timeout", query: "?q=Ivan", err: app.ErrTimeout, wantStatus: http.StatusGatewayTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &fakeService{
+				searchPersonInfo: func(ctx context.Context, query string) ([]*domain.PersonSearchResult, int, error) {
+					if tt.err != nil {
+						return nil, 0, tt.err
+					}
+					return results, 12, nil
+				},
+			}
+
+			w := serve(newTestRouter(t, svc, Options{}), httptest.NewRequest(http.MethodGet, "/person/search"+tt.query, nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if problem := decodeProblem(t, w); problem.Code != app.ErrorCode(tt.err) {
+					t.Errorf("code = %d, want %d", problem.Code, app.ErrorCode(tt.err))
+				}
+				return
+			}
+
+			var got SearchPersonInfoResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("bad body %s: %v", w.Body, err)
+			}
+			if got.TotalCount != 12 || len(got.Persons) != len(results) {
+				t.Fatalf("got %d persons of %d, want %d of 12", len(got.Persons), got.TotalCount, len(results))
+			}
+			for i, v := range results {
+				// The highlight is escaped by the repository and passed as is
+				if got.Persons[i].Id != v.Person.ID || got.Persons[i].Score != v.Score || got.Persons[i].Highlight != v.Highlight {
+					t.Errorf("person %d = %+v, want %+v", i, got.Persons[i], v)
+				}
+			}
+		})
+	}
+}
+
+func TestFieldErrors(t *testing.T) {
+	router := newTestRouter(t, &fakeService{}, Options{})
+
+	tests := []struct {
+		name           string
+		method         string
+		body           string
+		acceptLanguage string
+		want           []validation.FieldError
+	}{
+		{
+			name:   "add",
+			method: http.MethodPost,
+			body:   `{"name":"Ivan1","patronymic":"Ivanovich"}`,
+			want: []validation.FieldError{
+				{Field: "name", Rule: "person_name", Message: "name must contain only letters, hyphens and apostrophes"},
+				{Field: "surname", Rule: "required", Message: "surname is a required field"},
+			},
+		},
+		{
+			name:           "update in russian",
+			method:         http.MethodPut,
+			body:           `{"id":1,"name":"Ivan","surname":"Ivanov","gender":"other","age":250,"nationality":"ru"}`,
+			acceptLanguage: "ru-RU,ru;q=0.9",
+			want: []validation.FieldError{
+				{Field: "gender", Rule: "gender", Message: "gender должен быть одним из: male, female"},
+				{Field: "age", Rule: "lte", Param: "200", Message: "age должен быть менее или равен 200"},
+				{Field: "nationality", Rule: "iso3166_alpha2", Message: "nationality должен быть кодом страны ISO 3166-1 alpha-2"},
+			},
+		},
+		{
+			name:   "delete",
+			method: http.MethodDelete,
+			body:   `{"id":0}`,
+			want: []validation.FieldError{
+				{Field: "id", Rule: "required", Message: "id is a required field"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/person", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Accept-Language", tt.acceptLanguage)
+			w := serve(router, req)
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, http.StatusBadRequest, w.Body)
+			}
+
+			problem := decodeProblem(t, w)
+			if problem.Code != app.ErrorCode(app.ErrValidation) {
+				t.Errorf("code = %d, want %d", problem.Code, app.ErrorCode(app.ErrValidation))
+			}
+			details, _ := json.Marshal(problem.Details)
+			var got []validation.FieldError
+			if err := json.Unmarshal(details, &got); err != nil {
+				t.Fatalf("bad details %s: %v", details, err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("details = %+v, want %+v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("details[%d] = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestGetPersonStats(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		acceptLanguage string
+		stats          service.PersonStats
+		err            error
+		wantStatus     int
+		wantGroupBy    string
+		wantFilter     service.PersonFilter
+		want           GetPersonStatsResponse
+	}{
+		{
+			name:        "nationality names",
+			query:       "?continent=europe",
+			stats:       service.PersonStats{GroupBy: service.GroupByNationality, Groups: []service.PersonStat{{Key: "RU", Count: 5}, {Key: "", Count: 2}}},
+			wantStatus:  http.StatusOK,
+			wantGroupBy: service.GroupByNationality,
+			wantFilter:  service.PersonFilter{Continent: "europe"},
+			want: GetPersonStatsResponse{GroupBy: service.GroupByNationality, Total: 7, Groups: []PersonStatResponse{
+				{Key: "RU", Name: "Russian Federation", Count: 5}, {Key: "", Count: 2},
+			}},
+		},
+		{
+			name:           "nationality names in russian",
+			acceptLanguage: "ru",
+			stats:          service.PersonStats{GroupBy: service.GroupByNationality, Groups: []service.PersonStat{{Key: "DE", Count: 1}}},
+			wantStatus:     http.StatusOK,
+			wantGroupBy:    service.GroupByNationality,
+			want: GetPersonStatsResponse{GroupBy: service.GroupByNationality, Total: 1, Groups: []PersonStatResponse{
+				{Key: "DE", Name: "Германия", Count: 1},
+			}},
+		},
+		{
+			name:        "regions are not named",
+			query:       "?group_by=region&region=Eastern+Europe",
+			stats:       service.PersonStats{GroupBy: service.GroupByRegion, Groups: []service.PersonStat{{Key: "Eastern Europe", Count: 3}}},
+			wantStatus:  http.StatusOK,
+			wantGroupBy: service.GroupByRegion,
+			wantFilter:  service.PersonFilter{Region: "Eastern Europe"},
+			want: GetPersonStatsResponse{GroupBy: service.GroupByRegion, Total: 3, Groups: []PersonStatResponse{
+				{Key: "Eastern Europe", Count: 3},
+			}},
+		},
+		{
+			name:        "unknown group",
+			query:       "?group_by=planet",
+			err:         app.ErrInvalidParamType,
+			wantStatus:  http.StatusBadRequest,
+			wantGroupBy: "planet",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &fakeService{
+				getPersonStats: func(ctx context.Context, groupBy string, filter service.PersonFilter) (service.PersonStats, error) {
+					if groupBy != tt.wantGroupBy || filter != tt.wantFilter {
+						t.Errorf("stats of %q by %+v, want %q by %+v", groupBy, filter, tt.wantGroupBy, tt.wantFilter)
+					}
+					return tt.stats, tt.err
+				},
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/person/stats"+tt.query, nil)
+			req.Header.Set("Accept-Language", tt.acceptLanguage)
+			w := serve(newTestRouter(t, svc, Options{}), req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				decodeProblem(t, w)
+				return
+			}
+
+			var got GetPersonStatsResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("bad body %s: %v", w.Body, err)
+			}
+			if got.GroupBy != tt.want.GroupBy || got.Total != tt.want.Total || len(got.Groups) != len(tt.want.Groups) {
+				t.Fatalf("stats = %+v, want %+v", got, tt.want)
+			}
+			for i := range tt.want.Groups {
+				if got.Groups[i] != tt.want.Groups[i] {
+					t.Errorf("groups = %+v, want %+v", got.Groups, tt.want.Groups)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestGetCountries(t *testing.T) {
+	router := newTestRouter(t, &fakeService{}, Options{})
+
+	tests := []struct {
+		name           string
+		query          string
+		acceptLanguage string
+		wantStatus     int
+		wantLen        int
+		want           CountryResponse
+	}{
+		{
+			name:       "all",
+			wantStatus: http.StatusOK,
+			wantLen:    249,
+			want:       CountryResponse{Code: "RU", Alpha3: "RUS", Numeric: "643", Name: "Russian Federation", Region: "Eastern Europe", Continent: "Europe"},
+		},
+		{
+			name:           "region in russian",
+			query:          "?region=central+asia",
+			acceptLanguage: "ru-RU",
+			wantStatus:     http.StatusOK,
+			wantLen:        5,
+			want:           CountryResponse{Code: "KZ", Alpha3: "KAZ", Numeric: "398", Name: "Казахстан", Region: "Central Asia", Continent: "Asia"},
+		},
+		{
+			name:       "unknown continent",
+			query:      "?continent=atlantis",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/countries"+tt.query, nil)
+			req.Header.Set("Accept-Language", tt.acceptLanguage)
+			w := serve(router, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if problem := decodeProblem(t, w); problem.Code != app.ErrorCode(app.ErrInvalidParamType) {
+					t.Errorf("code = %d, want %d", problem.Code, app.ErrorCode(app.ErrInvalidParamType))
+				}
+				return
+			}
+
+			var got GetCountriesResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("bad body %s: %v", w.Body, err)
+			}
+			if len(got.Countries) != tt.wantLen {
+				t.Errorf("got %d countries, want %d", len(got.Countries), tt.wantLen)
+			}
+			for _, v := range got.Countries {
+				if v.Code == tt.want.Code {
+					if v != tt.want {
+						t.Errorf("country = %+v, want %+v", v, tt.want)
+					}
+					return
+				}
+			}
+			t.Errorf("countries have no %s", tt.want.Code)
+		})
+	}
+}
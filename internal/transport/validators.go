@@ -0,0 +1,99 @@
+package transport
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/maxik12233/task-junior/pkg/api/auth"
+	"github.com/maxik12233/task-junior/pkg/api/validation"
+	"github.com/maxik12233/task-junior/pkg/countries"
+)
+
+// Custom validation rules of the person fields.
+const (
+	rulePersonName  = "person_name"
+	ruleGender      = "gender"
+	ruleNationality = "iso3166_alpha2"
+	ruleScope       = "scope"
+)
+
+var allowedGenders = []string{"male", "female"}
+
+// NewValidator creates the validator shared by all handlers with the person rules registered.
+func NewValidator() (*validation.Validator, error) {
+	v, err := validation.New()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := v.RegisterRule(rulePersonName, validatePersonName, validation.Messages{
+		"en": "{0} must contain only letters, hyphens and apostrophes",
+		"ru": "{0} должно содержать только буквы, дефисы и апострофы",
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := v.RegisterRule(ruleGender, validateGender, validation.Messages{
+		"en": "{0} must be one of: " + strings.Join(allowedGenders, ", "),
+		"ru": "{0} должен быть одним из: " + strings.Join(allowedGenders, ", "),
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := v.RegisterRule(ruleNationality, validateNationality, validation.Messages{
+		"en": "{0} must be an ISO 3166-1 alpha-2 country code",
+		"ru": "{0} должен быть кодом страны ISO 3166-1 alpha-2",
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := v.RegisterRule(ruleScope, validateScope, validation.Messages{
+		"en": "{0} must be a role or a permission other than " + string(auth.PermissionTenantSwitch) + ", as api keys are bound to their tenant",
+		"ru": "{0} должен быть ролью или разрешением, кроме " + string(auth.PermissionTenantSwitch) + ", так как ключи api привязаны к своему тенанту",
+	}); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+func validatePersonName(fl validator.FieldLevel) bool {
+	name := fl.Field().String()
+	if name == "" {
+		return false
+	}
+
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		if r == '-' || r == '\'' || r == '’' {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+func validateGender(fl validator.FieldLevel) bool {
+	gender := fl.Field().String()
+	for _, v := range allowedGenders {
+		if gender == v {
+			return true
+		}
+	}
+	return false
+}
+
+func validateNationality(fl validator.FieldLevel) bool {
+	code := fl.Field().String()
+	return code == strings.ToUpper(code) && countries.IsAlpha2(code)
+}
+
+// validateScope checks the scope of the api key. Api keys are bound to the tenant
+// they are created in, so they can't carry auth.PermissionTenantSwitch.
+func validateScope(fl validator.FieldLevel) bool {
+	scope := fl.Field().String()
+	return auth.IsKnownScope(scope) && auth.Permission(scope) != auth.PermissionTenantSwitch
+}
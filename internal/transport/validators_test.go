@@ -0,0 +1,41 @@
+package transport
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateAPIKeyScopes(t *testing.T) {
+	v, err := NewValidator()
+	if err != nil {
+		t.Fatalf("NewValidator() error = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		scopes      []string
+		wantMessage string
+	}{
+		{name: "roles and permissions", scopes: []string{"viewer", "person:delete", "log:manage"}},
+		{name: "no scopes"},
+		{name: "unknown scope", scopes: []string{"owner"}, wantMessage: "must be a role or a permission"},
+		{name: "tenant switch", scopes: []string{"viewer", "tenant:switch"}, wantMessage: "api keys are bound to their tenant"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.Struct(CreateAPIKeyRequest{Name: "key", Scopes: tt.scopes})
+			if tt.wantMessage == "" {
+				if err != nil {
+					t.Errorf("Struct() error = %v", err)
+				}
+				return
+			}
+
+			fields := v.FieldErrors(err, "en")
+			if len(fields) != 1 || !strings.Contains(fields[0].Message, tt.wantMessage) {
+				t.Errorf("field errors = %+v, want one with %q", fields, tt.wantMessage)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	app "github.com/maxik12233/task-junior"
+	"github.com/maxik12233/task-junior/pkg/api"
+)
+
+const (
+	SubjectContextKey = "auth_subject"
+	ClaimsContextKey  = "auth_claims"
+	ScopesContextKey  = "auth_scopes"
+	TenantContextKey  = "auth_tenant"
+
+	APIKeyHeader = "X-API-Key"
+	// TenantClaim is the JWT claim which binds the token to a tenant.
+	TenantClaim = "tenant_id"
+)
+
+// Identity is the caller identified by the credentials. Empty Tenant
+// means that the credentials are not bound to a tenant.
+type Identity struct {
+	Subject string
+	Scopes  []string
+	Tenant  string
+}
+
+// APIKeyVerifier checks the API key and returns the identity of its owner.
+// Unknown or inactive keys are reported with app.ErrUnauthorized.
+type APIKeyVerifier interface {
+	VerifyAPIKey(ctx context.Context, key string) (Identity, error)
+}
+
+// Config holds the keys tokens are verified with. At least one of Secret,
+// PublicKeyFile, JWKSFile or APIKeys must be set. Empty Issuer or Audience are not checked.
+// Tokens without the exp claim are rejected.
+type Config struct {
+	// Secret is the HS256 shared secret.
+	Secret string
+	// PublicKeyFile is the PEM encoded RS256 public key.
+	PublicKeyFile string
+	// JWKSFile is the local JSON Web Key Set with RSA and oct keys.
+	JWKSFile string
+	Issuer   string
+	Audience string
+	// Leeway is the allowed clock skew for the exp, nbf and iat claims.
+	Leeway time.Duration
+	// APIKeys verifies keys from the X-API-Key header. If nil, API keys are not accepted.
+	APIKeys APIKeyVerifier
+}
+
+// Authenticator verifies HS256 and RS256 signed JWTs and API keys.
+type Authenticator struct {
+	keys    keySet
+	parser  *jwt.Parser
+	apiKeys APIKeyVerifier
+}
+
+func New(cfg Config) (*Authenticator, error) {
+	keys := keySet{
+		hmac: make(map[string][]byte),
+		rsa:  make(map[string]interface{}),
+	}
+
+	if cfg.Secret != "" {
+		keys.hmac[""] = []byte(cfg.Secret)
+	}
+	if cfg.PublicKeyFile != "" {
+		key, err := loadPublicKey(cfg.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		keys.rsa[""] = key
+	}
+	if cfg.JWKSFile != "" {
+		if err := keys.loadJWKS(cfg.JWKSFile); err != nil {
+			return nil, err
+		}
+	}
+	if len(keys.hmac) == 0 && len(keys.rsa) == 0 && cfg.APIKeys == nil {
+		return nil, errors.New("auth: no keys configured")
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg(), jwt.SigningMethodRS256.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(cfg.Leeway),
+	}
+	if cfg.Issuer != "" {
+		options = append(options, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		options = append(options, jwt.WithAudience(cfg.Audience))
+	}
+
+	return &Authenticator{
+		keys:    keys,
+		parser:  jwt.NewParser(options...),
+		apiKeys: cfg.APIKeys,
+	}, nil
+}
+
+// Authenticate verifies the token and returns its claims.
+func (a *Authenticator) Authenticate(token string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	if _, err := a.parser.ParseWithClaims(token, claims, a.keys.keyFunc); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// Middleware rejects requests without a valid API key or bearer token and puts
+// the subject, scopes, tenant and token claims into the request context.
+func (a *Authenticator) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var (
+			identity Identity
+			claims   jwt.MapClaims
+		)
+
+		if key := c.GetHeader(APIKeyHeader); key != "" {
+			if a.apiKeys == nil {
+				abort(c, app.WrapE(app.ErrUnauthorized, "api keys are not accepted"))
+				return
+			}
+
+			var err error
+			identity, err = a.apiKeys.VerifyAPIKey(c.Request.Context(), key)
+			if err != nil {
+				abort(c, err)
+				return
+			}
+		} else {
+			scheme, token, _ := strings.Cut(c.GetHeader("Authorization"), " ")
+			if !strings.EqualFold(scheme, "Bearer") || token == "" {
+				abort(c, app.WrapE(app.ErrUnauthorized, "bearer token or api key is required"))
+				return
+			}
+
+			var err error
+			claims, err = a.Authenticate(strings.TrimSpace(token))
+			if err != nil {
+				abort(c, app.WrapE(app.ErrUnauthorized, fmt.Sprintf("invalid token: %s", err)))
+				return
+			}
+
+			identity.Subject, _ = claims.GetSubject()
+			identity.Scopes = claimScopes(claims)
+			identity.Tenant, _ = claims[TenantClaim].(string)
+		}
+
+		ctx := context.WithValue(c.Request.Context(), SubjectContextKey, identity.Subject)
+		ctx = context.WithValue(ctx, ScopesContextKey, identity.Scopes)
+		ctx = context.WithValue(ctx, TenantContextKey, identity.Tenant)
+		if claims != nil {
+			ctx = context.WithValue(ctx, ClaimsContextKey, claims)
+		}
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
+
+// claimScopes reads scopes from the space separated "scope" claim or the "scp" list claim.
+func claimScopes(claims jwt.MapClaims) []string {
+	if scope, ok := claims["scope"].(string); ok {
+		return strings.Fields(scope)
+	}
+
+	list, _ := claims["scp"].([]interface{})
+	scopes := make([]string, 0, len(list))
+	for _, v := range list {
+		if scope, ok := v.(string); ok {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+func abort(c *gin.Context, err error) {
+	c.Header("WWW-Authenticate", `Bearer realm="api"`)
+	api.AbortWithError(c, err)
+}
+
+// Subject returns the subject of the authenticated request, or empty string.
+func Subject(ctx context.Context) string {
+	subject, _ := ctx.Value(SubjectContextKey).(string)
+	return subject
+}
+
+// Claims returns the claims of the authenticated request, or nil.
+func Claims(ctx context.Context) jwt.MapClaims {
+	claims, _ := ctx.Value(ClaimsContextKey).(jwt.MapClaims)
+	return claims
+}
+
+// Scopes returns the scopes of the authenticated request.
+func Scopes(ctx context.Context) []string {
+	scopes, _ := ctx.Value(ScopesContextKey).([]string)
+	return scopes
+}
+
+func HasScope(ctx context.Context, scope string) bool {
+	for _, v := range Scopes(ctx) {
+		if v == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Tenant returns the tenant the credentials of the request are bound to, or empty string.
+func Tenant(ctx context.Context) string {
+	tenant, _ := ctx.Value(TenantContextKey).(string)
+	return tenant
+}
@@ -0,0 +1,373 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	app "github.com/maxik12233/task-junior"
+)
+
+const (
+	testSecret   = "secret"
+	testIssuer   = "https://issuer.example"
+	testAudience = "task-junior"
+	testLeeway   = 30 * time.Second
+)
+
+// apiKeyVerifier accepts the keys of the map.
+type apiKeyVerifier map[string]Identity
+
+func (v apiKeyVerifier) VerifyAPIKey(ctx context.Context, key string) (Identity, error) {
+	identity, ok := v[key]
+	if !ok {
+		return Identity{}, app.WrapE(app.ErrUnauthorized, "invalid api key")
+	}
+	return identity, nil
+}
+
+// authFixture holds the keys the test authenticator trusts: the secret, the PEM
+// public key file of fileKey, and the JWKS file with jwksKey as "rsa-1" and the
+// "oct-1" secret. otherKey is not trusted.
+type authFixture struct {
+	fileKey    *rsa.PrivateKey
+	jwksKey    *rsa.PrivateKey
+	otherKey   *rsa.PrivateKey
+	jwksSecret []byte
+	publicPEM  []byte
+	auth       *Authenticator
+}
+
+func newAuthFixture(t *testing.T) *authFixture {
+	t.Helper()
+
+	f := &authFixture{jwksSecret: []byte("jwks-secret")}
+	for _, key := range []**rsa.PrivateKey{&f.fileKey, &f.jwksKey, &f.otherKey} {
+		var err error
+		if *key, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+			t.Fatalf("GenerateKey() error = %v", err)
+		}
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(&f.fileKey.PublicKey)
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey() error = %v", err)
+	}
+	f.publicPEM = pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	jwks, err := json.Marshal(map[string][]jwk{"keys": {
+		{
+			Kty: "RSA",
+			Kid: "rsa-1",
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(f.jwksKey.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(f.jwksKey.E)).Bytes()),
+		},
+		{Kty: "oct", Kid: "oct-1", K: base64.RawURLEncoding.EncodeToString(f.jwksSecret)},
+		{Kty: "RSA", Kid: "enc-1", Use: "enc", N: "AQAB", E: "AQAB"},
+	}})
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	dir := t.TempDir()
+	publicKeyFile := filepath.Join(dir, "public.pem")
+	jwksFile := filepath.Join(dir, "jwks.json")
+	if err := os.WriteFile(publicKeyFile, f.publicPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(jwksFile, jwks, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	f.auth, err = New(Config{
+		Secret:        testSecret,
+		PublicKeyFile: publicKeyFile,
+		JWKSFile:      jwksFile,
+		Issuer:        testIssuer,
+		Audience:      testAudience,
+		Leeway:        testLeeway,
+		APIKeys: apiKeyVerifier{
+			"tj_valid": {Subject: "key:1", Scopes: []string{"viewer"}, Tenant: "team-a"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return f
+}
+
+// claims returns valid claims of the subject, changed by the overrides.
+// A nil override deletes the claim.
+func claims(overrides jwt.MapClaims) jwt.MapClaims {
+	now := time.Now()
+	result := jwt.MapClaims{
+		"sub":   "user-1",
+		"iss":   testIssuer,
+		"aud":   testAudience,
+		"exp":   now.Add(time.Hour).Unix(),
+		"iat":   now.Unix(),
+		"scope": "viewer editor",
+	}
+	for k, v := range overrides {
+		if v == nil {
+			delete(result, k)
+			continue
+		}
+		result[k] = v
+	}
+	return result
+}
+
+// sign signs the claims with the method and the key, and sets kid if it isn't empty.
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+	return signed
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	f := newAuthFixture(t)
+
+	tests := []struct {
+		name        string
+		apiKey      string
+		bearer      string
+		wantStatus  int
+		wantSubject string
+		wantScopes  []string
+		wantTenant  string
+	}{
+		{
+			name:        "HS256 secret",
+			bearer:      sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(jwt.MapClaims{"tenant_id": "team-a"})),
+			wantStatus:  http.StatusOK,
+			wantSubject: "user-1",
+			wantScopes:  []string{"viewer", "editor"},
+			wantTenant:  "team-a",
+		},
+		{
+			name:        "RS256 public key file",
+			bearer:      sign(t, jwt.SigningMethodRS256, f.fileKey, "", claims(jwt.MapClaims{"scope": nil, "scp": []string{"admin"}})),
+			wantStatus:  http.StatusOK,
+			wantSubject: "user-1",
+			wantScopes:  []string{"admin"},
+		},
+		{
+			name:        "RS256 jwks key by kid",
+			bearer:      sign(t, jwt.SigningMethodRS256, f.jwksKey, "rsa-1", claims(nil)),
+			wantStatus:  http.StatusOK,
+			wantSubject: "user-1",
+			wantScopes:  []string{"viewer", "editor"},
+		},
+		{
+			name:        "HS256 jwks key by kid",
+			bearer:      sign(t, jwt.SigningMethodHS256, f.jwksSecret, "oct-1", claims(nil)),
+			wantStatus:  http.StatusOK,
+			wantSubject: "user-1",
+			wantScopes:  []string{"viewer", "editor"},
+		},
+		{
+			name:       "kid of another key",
+			bearer:     sign(t, jwt.SigningMethodRS256, f.fileKey, "rsa-1", claims(nil)),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "unknown kid",
+			bearer:     sign(t, jwt.SigningMethodRS256, f.jwksKey, "rsa-2", claims(nil)),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "kid of encryption key",
+			bearer:     sign(t, jwt.SigningMethodRS256, f.jwksKey, "enc-1", claims(nil)),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "untrusted RSA key",
+			bearer:     sign(t, jwt.SigningMethodRS256, f.otherKey, "", claims(nil)),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "wrong secret",
+			bearer:     sign(t, jwt.SigningMethodHS256, []byte("other"), "", claims(nil)),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "HS256 signed with the RSA public key",
+			bearer:     sign(t, jwt.SigningMethodHS256, f.publicPEM, "", claims(nil)),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "HS256 signed with the RSA public key under its kid",
+			bearer:     sign(t, jwt.SigningMethodHS256, f.publicPEM, "rsa-1", claims(nil)),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "alg none",
+			bearer:     sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "", claims(nil)),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "alg outside the allowed ones",
+			bearer:     sign(t, jwt.SigningMethodHS512, []byte(testSecret), "", claims(nil)),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "no exp",
+			bearer:     sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(jwt.MapClaims{"exp": nil})),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:        "expired within leeway",
+			bearer:      sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(jwt.MapClaims{"exp": time.Now().Add(-testLeeway / 2).Unix()})),
+			wantStatus:  http.StatusOK,
+			wantSubject: "user-1",
+			wantScopes:  []string{"viewer", "editor"},
+		},
+		{
+			name:       "expired over leeway",
+			bearer:     sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(jwt.MapClaims{"exp": time.Now().Add(-2 * testLeeway).Unix()})),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:        "not before within leeway",
+			bearer:      sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(jwt.MapClaims{"nbf": time.Now().Add(testLeeway / 2).Unix()})),
+			wantStatus:  http.StatusOK,
+			wantSubject: "user-1",
+			wantScopes:  []string{"viewer", "editor"},
+		},
+		{
+			name:       "not before over leeway",
+			bearer:     sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(jwt.MapClaims{"nbf": time.Now().Add(time.Hour).Unix()})),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "wrong issuer",
+			bearer:     sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(jwt.MapClaims{"iss": "https://other.example"})),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "no issuer",
+			bearer:     sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(jwt.MapClaims{"iss": nil})),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "wrong audience",
+			bearer:     sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(jwt.MapClaims{"aud": []string{"other"}})),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:        "audience in list",
+			bearer:      sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(jwt.MapClaims{"aud": []string{"other", testAudience}})),
+			wantStatus:  http.StatusOK,
+			wantSubject: "user-1",
+			wantScopes:  []string{"viewer", "editor"},
+		},
+		{
+			name:       "not a jwt",
+			bearer:     "not-a-token",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:        "api key",
+			apiKey:      "tj_valid",
+			wantStatus:  http.StatusOK,
+			wantSubject: "key:1",
+			wantScopes:  []string{"viewer"},
+			wantTenant:  "team-a",
+		},
+		{
+			name:        "api key takes precedence over bad bearer",
+			apiKey:      "tj_valid",
+			bearer:      "not-a-token",
+			wantStatus:  http.StatusOK,
+			wantSubject: "key:1",
+			wantScopes:  []string{"viewer"},
+			wantTenant:  "team-a",
+		},
+		{
+			name:       "bad api key takes precedence over valid bearer",
+			apiKey:     "tj_invalid",
+			bearer:     sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(nil)),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "no credentials",
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				subject, tenant string
+				scopes          []string
+			)
+			router := gin.New()
+			router.GET("/", f.auth.Middleware(), func(c *gin.Context) {
+				subject = Subject(c.Request.Context())
+				scopes = Scopes(c.Request.Context())
+				tenant = Tenant(c.Request.Context())
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.apiKey != "" {
+				req.Header.Set(APIKeyHeader, tt.apiKey)
+			}
+			if tt.bearer != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.bearer)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if w.Code != http.StatusOK {
+				if got := w.Header().Get("WWW-Authenticate"); got == "" {
+					t.Error("WWW-Authenticate header is missing")
+				}
+				if strings.Contains(w.Body.String(), "%!") {
+					t.Errorf("body has format errors: %s", w.Body)
+				}
+				return
+			}
+			if subject != tt.wantSubject || tenant != tt.wantTenant || strings.Join(scopes, " ") != strings.Join(tt.wantScopes, " ") {
+				t.Errorf("identity = %q %v %q, want %q %v %q", subject, scopes, tenant, tt.wantSubject, tt.wantScopes, tt.wantTenant)
+			}
+		})
+	}
+}
+
+func TestNewRequiresKeys(t *testing.T) {
+	if _, err := New(Config{}); err == nil {
+		t.Error("New() without keys error = nil")
+	}
+	if _, err := New(Config{APIKeys: apiKeyVerifier{}}); err != nil {
+		t.Errorf("New() with api keys only error = %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// keySet holds verification keys by their key id. Keys from the config
+// have an empty id and are used for tokens without the kid header.
+type keySet struct {
+	hmac map[string][]byte
+	rsa  map[string]interface{}
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	// RSA public key
+	N string `json:"n"`
+	E string `json:"e"`
+	// Symmetric key
+	K string `json:"k"`
+}
+
+func (k keySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	var (
+		key interface{}
+		ok  bool
+	)
+	switch token.Method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		key, ok = k.hmac[kid]
+	case jwt.SigningMethodRS256.Alg():
+		key, ok = k.rsa[kid]
+	}
+	if !ok {
+		return nil, fmt.Errorf("no %s key with id %q", token.Method.Alg(), kid)
+	}
+
+	return key, nil
+}
+
+func loadPublicKey(path string) (*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("auth: reading public key: %w", err)
+	}
+
+	key, err := jwt.ParseRSAPublicKeyFromPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("auth: parsing public key: %w", err)
+	}
+	return key, nil
+}
+
+func (k keySet) loadJWKS(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("auth: reading jwks: %w", err)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return fmt.Errorf("auth: parsing jwks: %w", err)
+	}
+
+	for _, v := range set.Keys {
+		if v.Use != "" && v.Use != "sig" {
+			continue
+		}
+
+		switch v.Kty {
+		case "RSA":
+			key, err := v.rsaPublicKey()
+			if err != nil {
+				return fmt.Errorf("auth: jwks key %q: %w", v.Kid, err)
+			}
+			k.rsa[v.Kid] = key
+		case "oct":
+			key, err := base64.RawURLEncoding.DecodeString(v.K)
+			if err != nil {
+				return fmt.Errorf("auth: jwks key %q: %w", v.Kid, err)
+			}
+			k.hmac[v.Kid] = key
+		}
+	}
+
+	return nil
+}
+
+func (v jwk) rsaPublicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(v.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(v.E)
+	if err != nil {
+		return nil, err
+	}
+
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 || exponent.Int64() < 3 {
+		return nil, errors.New("bad exponent")
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(exponent.Int64()),
+	}, nil
+}
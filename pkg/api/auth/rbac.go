@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	app "github.com/maxik12233/task-junior"
+	"github.com/maxik12233/task-junior/pkg/api"
+	log "github.com/maxik12233/task-junior/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type Permission string
+
+const (
+	PermissionPersonRead   Permission = "person:read"
+	PermissionPersonWrite  Permission = "person:write"
+	PermissionPersonDelete Permission = "person:delete"
+	PermissionAPIKeyManage Permission = "api_key:manage"
+	PermissionLogManage    Permission = "log:manage"
+	// PermissionTenantSwitch lets credentials not bound to a tenant pick it by the
+	// X-Tenant-ID header. No role grants it, it must be given as a scope.
+	PermissionTenantSwitch Permission = "tenant:switch"
+)
+
+type Role string
+
+const (
+	RoleViewer Role = "viewer"
+	RoleEditor Role = "editor"
+	RoleAdmin  Role = "admin"
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleViewer: {PermissionPersonRead},
+	RoleEditor: {PermissionPersonRead, PermissionPersonWrite},
+	RoleAdmin:  {PermissionPersonRead, PermissionPersonWrite, PermissionPersonDelete, PermissionAPIKeyManage, PermissionLogManage},
+}
+
+// standalonePermissions are granted by no role, only by their own scope.
+var standalonePermissions = []Permission{PermissionTenantSwitch}
+
+// PermissionDenied is the problem details extension of the denied request.
+type PermissionDenied struct {
+	RequiredPermission Permission `json:"required_permission"`
+}
+
+// HasPermission reports whether the request scopes grant the permission,
+// either by a role scope or by the permission scope itself.
+func HasPermission(ctx context.Context, permission Permission) bool {
+	for _, scope := range Scopes(ctx) {
+		if Permission(scope) == permission {
+			return true
+		}
+		for _, v := range rolePermissions[Role(scope)] {
+			if v == permission {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RequirePermission rejects authenticated requests which lack the permission.
+func RequirePermission(logger *zap.Logger, permission Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		if !HasPermission(ctx, permission) {
+			log.FromContext(ctx, logger).Warn("Permission denied",
+				zap.String("subject", Subject(ctx)),
+				zap.Strings("scopes", Scopes(ctx)),
+				zap.String("permission", string(permission)),
+				zap.String("method", c.Request.Method),
+				zap.String("path", c.FullPath()),
+			)
+			api.AbortWithErrorDetails(c,
+				app.WrapE(app.ErrForbidden, fmt.Sprintf("permission %q is required", permission)),
+				PermissionDenied{RequiredPermission: permission})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// IsKnownScope reports whether the scope is a role or a permission.
+func IsKnownScope(scope string) bool {
+	if _, ok := rolePermissions[Role(scope)]; ok {
+		return true
+	}
+	for _, v := range standalonePermissions {
+		if Permission(scope) == v {
+			return true
+		}
+	}
+	for _, permissions := range rolePermissions {
+		for _, v := range permissions {
+			if Permission(scope) == v {
+				return true
+			}
+		}
+	}
+	return false
+}
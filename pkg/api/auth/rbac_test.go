@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func withScopes(scopes ...string) context.Context {
+	return context.WithValue(context.Background(), ScopesContextKey, scopes)
+}
+
+func TestHasPermission(t *testing.T) {
+	allPermissions := []Permission{
+		PermissionPersonRead, PermissionPersonWrite, PermissionPersonDelete,
+		PermissionAPIKeyManage, PermissionLogManage, PermissionTenantSwitch,
+	}
+
+	tests := []struct {
+		name   string
+		scopes []string
+		want   []Permission
+	}{
+		{name: "no scopes"},
+		{name: "viewer", scopes: []string{"viewer"}, want: []Permission{PermissionPersonRead}},
+		{name: "editor", scopes: []string{"editor"}, want: []Permission{PermissionPersonRead, PermissionPersonWrite}},
+		{name: "admin", scopes: []string{"admin"}, want: []Permission{
+			PermissionPersonRead, PermissionPersonWrite, PermissionPersonDelete, PermissionAPIKeyManage, PermissionLogManage,
+		}},
+		{name: "permission scope", scopes: []string{"person:delete"}, want: []Permission{PermissionPersonDelete}},
+		{name: "role and permission", scopes: []string{"viewer", "tenant:switch"}, want: []Permission{PermissionPersonRead, PermissionTenantSwitch}},
+		{name: "unknown scope", scopes: []string{"superuser", "Admin"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := withScopes(tt.scopes...)
+
+			granted := make(map[Permission]bool)
+			for _, v := range tt.want {
+				granted[v] = true
+			}
+			for _, permission := range allPermissions {
+				if got := HasPermission(ctx, permission); got != granted[permission] {
+					t.Errorf("HasPermission(%v, %q) = %v, want %v", tt.scopes, permission, got, granted[permission])
+				}
+			}
+		})
+	}
+}
+
+func TestIsKnownScope(t *testing.T) {
+	tests := []struct {
+		scope string
+		want  bool
+	}{
+		{scope: "viewer", want: true},
+		{scope: "admin", want: true},
+		{scope: "person:write", want: true},
+		{scope: "log:manage", want: true},
+		{scope: "tenant:switch", want: true},
+		{scope: "owner", want: false},
+		{scope: "", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := IsKnownScope(tt.scope); got != tt.want {
+			t.Errorf("IsKnownScope(%q) = %v, want %v", tt.scope, got, tt.want)
+		}
+	}
+}
+
+func TestRequirePermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name   string
+		scopes []string
+		want   int
+	}{
+		{name: "granted", scopes: []string{"editor"}, want: http.StatusOK},
+		{name: "denied", scopes: []string{"viewer"}, want: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				c.Request = c.Request.WithContext(withScopes(tt.scopes...))
+			})
+			router.POST("/person", RequirePermission(zap.NewNop(), PermissionPersonWrite), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/person", nil))
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
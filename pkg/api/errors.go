@@ -1,12 +1,39 @@
 package api
 
-import "encoding/json"
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"regexp"
 
+	"github.com/gin-gonic/gin"
+	app "github.com/maxik12233/task-junior"
+)
+
+const (
+	ProblemContentType = "application/problem+json"
+
+	RequestIdHeader = "X-Request-ID"
+	RequestIdKey    = "request_id"
+)
+
+// ErrorResponse is the RFC 7807 problem details object extended with
+// the application error code and the request id.
 type ErrorResponse struct {
-	Message string          `json:"message"`
-	Details json.RawMessage `json:"details"`
+	Type      string      `json:"type"`
+	Title     string      `json:"title"`
+	Status    int         `json:"status"`
+	Detail    string      `json:"detail,omitempty"`
+	Instance  string      `json:"instance,omitempty"`
+	Code      int         `json:"code"`
+	RequestId string      `json:"request_id,omitempty"`
+	Details   interface{} `json:"details,omitempty"`
 }
 
+// requestIdPattern limits the ids taken from the header, as they are written to logs.
+var requestIdPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
 type JSONMarshal interface {
 	MarshalJSON() ([]byte, error)
 	UnmarshalJSON(data []byte) error
@@ -19,3 +46,57 @@ func (e *ErrorResponse) Marshal() []byte {
 	}
 	return marshal
 }
+
+func statusText(status int) string {
+	if status == app.StatusClientClosedRequest {
+		return "Client Closed Request"
+	}
+	return http.StatusText(status)
+}
+
+// NewErrorResponse builds problem details for the application error.
+func NewErrorResponse(c *gin.Context, err error, details interface{}) *ErrorResponse {
+	status := app.GetHTTPCodeFromError(err)
+	return &ErrorResponse{
+		Type:      "about:blank",
+		Title:     statusText(status),
+		Status:    status,
+		Detail:    err.Error(),
+		Instance:  c.Request.URL.Path,
+		Code:      app.ErrorCode(err),
+		RequestId: RequestId(c),
+		Details:   details,
+	}
+}
+
+// AbortWithError writes the application error as application/problem+json and aborts the chain.
+func AbortWithError(c *gin.Context, err error) {
+	AbortWithErrorDetails(c, err, nil)
+}
+
+func AbortWithErrorDetails(c *gin.Context, err error, details interface{}) {
+	problem := NewErrorResponse(c, err, details)
+	c.Header("Content-Type", ProblemContentType)
+	c.AbortWithStatus(problem.Status)
+	c.Writer.Write(problem.Marshal())
+}
+
+// RequestId returns the id of the current request taken from the X-Request-ID
+// header, or generates a new one if there is none or it is malformed.
+// The id is echoed in the response header.
+func RequestId(c *gin.Context) string {
+	if id := c.GetString(RequestIdKey); id != "" {
+		return id
+	}
+
+	id := c.GetHeader(RequestIdHeader)
+	if !requestIdPattern.MatchString(id) {
+		b := make([]byte, 16)
+		rand.Read(b)
+		id = hex.EncodeToString(b)
+	}
+
+	c.Set(RequestIdKey, id)
+	c.Header(RequestIdHeader, id)
+	return id
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	app "github.com/maxik12233/task-junior"
+)
+
+func TestAbortWithError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		err        error
+		details    interface{}
+		wantStatus int
+		wantTitle  string
+		wantCode   int
+	}{
+		{name: "not found", err: app.WrapE(app.ErrNotFound, "person 7"), wantStatus: http.StatusNotFound, wantTitle: "Not Found", wantCode: app.ErrorCode(app.ErrNotFound)},
+		{name: "duplicate", err: app.ErrDuplicate, wantStatus: http.StatusConflict, wantTitle: "Conflict", wantCode: app.ErrorCode(app.ErrDuplicate)},
+		{name: "validation with details", err: app.ErrValidation, details: []string{"name"}, wantStatus: http.StatusBadRequest, wantTitle: "Bad Request", wantCode: app.ErrorCode(app.ErrValidation)},
+		{name: "timeout", err: app.ErrTimeout, wantStatus: http.StatusGatewayTimeout, wantTitle: "Gateway Timeout", wantCode: app.ErrorCode(app.ErrTimeout)},
+		{name: "canceled", err: app.ErrCanceled, wantStatus: app.StatusClientClosedRequest, wantTitle: "Client Closed Request", wantCode: app.ErrorCode(app.ErrCanceled)},
+		{name: "unknown error", err: errors.New("boom"), wantStatus: http.StatusBadRequest, wantTitle: "Bad Request", wantCode: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			router := gin.New()
+			router.GET("/person", func(c *gin.Context) {
+				AbortWithErrorDetails(c, tt.err, tt.details)
+			}, func(c *gin.Context) {
+				called = true
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/person?id=7", nil)
+			req.Header.Set(RequestIdHeader, "req-1")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if called {
+				t.Error("the chain is not aborted")
+			}
+			if got := w.Header().Get("Content-Type"); got != ProblemContentType {
+				t.Errorf("Content-Type = %q, want %q", got, ProblemContentType)
+			}
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("bad body %s: %v", w.Body, err)
+			}
+			want := map[string]interface{}{
+				"type":       "about:blank",
+				"title":      tt.wantTitle,
+				"status":     float64(tt.wantStatus),
+				"detail":     tt.err.Error(),
+				"instance":   "/person",
+				"code":       float64(tt.wantCode),
+				"request_id": "req-1",
+			}
+			for k, v := range want {
+				if got[k] != v {
+					t.Errorf("%s = %v, want %v", k, got[k], v)
+				}
+			}
+			if _, ok := got["details"]; ok != (tt.details != nil) {
+				t.Errorf("details = %v, want %v", got["details"], tt.details)
+			}
+		})
+	}
+}
+
+func TestRequestId(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	generated := regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{name: "header", header: "3f2c-41:a.b_c", want: "3f2c-41:a.b_c"},
+		{name: "no header"},
+		{name: "spaces", header: "id with spaces"},
+		{name: "log injection", header: "id\nlevel=error"},
+		{name: "over 128 characters", header: strings.Repeat("a", 129)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var first, second string
+			router := gin.New()
+			router.GET("/", func(c *gin.Context) {
+				first, second = RequestId(c), RequestId(c)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(RequestIdHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if tt.want != "" && first != tt.want {
+				t.Errorf("RequestId() = %q, want %q", first, tt.want)
+			}
+			if tt.want == "" && !generated.MatchString(first) {
+				t.Errorf("RequestId() = %q, want a generated id", first)
+			}
+			if second != first {
+				t.Errorf("second RequestId() = %q, want %q", second, first)
+			}
+			if got := w.Header().Get(RequestIdHeader); got != first {
+				t.Errorf("%s header = %q, want %q", RequestIdHeader, got, first)
+			}
+		})
+	}
+}
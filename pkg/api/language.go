@@ -0,0 +1,43 @@
+package api
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ParseAcceptLanguage returns language tags from the header in the given order,
+// each followed by its primary subtag, e.g. "ru-RU" -> "ru-ru", "ru".
+func ParseAcceptLanguage(header string) []string {
+	var locales []string
+	for _, part := range strings.Split(header, ",") {
+		tag, _, _ := strings.Cut(part, ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		locales = append(locales, tag)
+		if primary, _, ok := strings.Cut(tag, "-"); ok {
+			locales = append(locales, primary)
+		}
+	}
+	return locales
+}
+
+// Locale returns the first of the supported locales preferred by the
+// Accept-Language header of the request, or the first supported one.
+func Locale(c *gin.Context, supported ...string) string {
+	for _, locale := range ParseAcceptLanguage(c.GetHeader("Accept-Language")) {
+		for _, v := range supported {
+			if locale == v {
+				return v
+			}
+		}
+	}
+
+	if len(supported) == 0 {
+		return ""
+	}
+	return supported[0]
+}
@@ -1,39 +1,150 @@
 package logging
 
 import (
-	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/maxik12233/task-junior/pkg/api/auth"
+	log "github.com/maxik12233/task-junior/pkg/logger"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
-func RequestLogger(logger *zap.Logger) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		t := time.Now()
+const redacted = "REDACTED"
 
-		c.Next()
+// Sensitive query params and headers, which are always redacted.
+var (
+	DefaultRedactedQuery   = []string{"apikey", "api_key", "token", "access_token", "password"}
+	DefaultRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", auth.APIKeyHeader}
+)
+
+// Config of the access log. Zero values keep the defaults.
+type Config struct {
+	// SuccessSampleRate is the share of logged requests with status below 400.
+	// Zero means 1, all are logged. Failed and slow requests are always logged.
+	SuccessSampleRate float64
+	// SlowThreshold makes slower requests always logged. Zero disables it.
+	SlowThreshold time.Duration
+	// Headers are the request headers added to the entries.
+	Headers []string
+	// RedactQuery and RedactHeaders are redacted in addition to the defaults.
+	RedactQuery   []string
+	RedactHeaders []string
+}
 
-		latency := time.Since(t)
+type accessLogger struct {
+	logger        *zap.Logger
+	config        Config
+	redactQuery   map[string]bool
+	redactHeaders map[string]bool
+}
 
-		message := fmt.Sprintf("REQUEST:: Method: %s; URI: %s; Proto:  %s; Latency: %s;\n",
-			c.Request.Method,
-			c.Request.RequestURI,
-			c.Request.Proto,
-			latency)
-		logger.Info(message)
+// AccessLogger writes one entry per request with its route template, status,
+// latency, size, client and user. The entry has the level of the status:
+// info for success, warn for 4xx and error for 5xx.
+func AccessLogger(logger *zap.Logger, config Config) gin.HandlerFunc {
+	l := accessLogger{
+		logger:        logger,
+		config:        config,
+		redactQuery:   make(map[string]bool),
+		redactHeaders: make(map[string]bool),
 	}
+	for _, v := range append(DefaultRedactedQuery, config.RedactQuery...) {
+		l.redactQuery[strings.ToLower(v)] = true
+	}
+	for _, v := range append(DefaultRedactedHeaders, config.RedactHeaders...) {
+		l.redactHeaders[http.CanonicalHeaderKey(v)] = true
+	}
+
+	return l.handle
 }
 
-func ResponseLogger(logger *zap.Logger) gin.HandlerFunc {
-	return func(c *gin.Context) {
+func (l accessLogger) handle(c *gin.Context) {
+	start := time.Now()
 
-		c.Next()
+	c.Next()
+
+	latency := time.Since(start)
+	status := c.Writer.Status()
+	if !l.sampled(status, latency) {
+		return
+	}
+
+	fields := []zap.Field{
+		zap.String("method", c.Request.Method),
+		zap.String("route", c.FullPath()),
+		zap.String("path", c.Request.URL.Path),
+		zap.Int("status", status),
+		zap.Duration("latency", latency),
+		zap.Int("bytes", max(c.Writer.Size(), 0)),
+		zap.String("client_ip", c.ClientIP()),
+		zap.String("user_agent", c.Request.UserAgent()),
+	}
+	if query := l.query(c.Request.URL); query != "" {
+		fields = append(fields, zap.String("query", query))
+	}
+	if subject := auth.Subject(c.Request.Context()); subject != "" {
+		fields = append(fields, zap.String("user_id", subject))
+	}
+	if len(l.config.Headers) > 0 {
+		fields = append(fields, zap.Object("headers", l.headers(c.Request.Header)))
+	}
+	if len(c.Errors) > 0 {
+		fields = append(fields, zap.Strings("errors", c.Errors.Errors()))
+	}
+
+	level := zapcore.InfoLevel
+	switch {
+	case status >= http.StatusInternalServerError:
+		level = zapcore.ErrorLevel
+	case status >= http.StatusBadRequest:
+		level = zapcore.WarnLevel
+	}
+	log.FromContext(c.Request.Context(), l.logger).Log(level, "Request", fields...)
+}
+
+func (l accessLogger) sampled(status int, latency time.Duration) bool {
+	if status >= http.StatusBadRequest {
+		return true
+	}
+	if l.config.SlowThreshold > 0 && latency >= l.config.SlowThreshold {
+		return true
+	}
+	rate := l.config.SuccessSampleRate
+	return rate <= 0 || rate >= 1 || rand.Float64() < rate
+}
+
+// query returns the raw query with the values of sensitive params redacted.
+func (l accessLogger) query(u *url.URL) string {
+	if u.RawQuery == "" {
+		return ""
+	}
+
+	values := u.Query()
+	for key := range values {
+		if l.redactQuery[strings.ToLower(key)] {
+			values[key] = []string{redacted}
+		}
+	}
+	return values.Encode()
+}
 
-		message := fmt.Sprintf("RESPONSE:: Status: %d; Method: %s; URI: %s;\n",
-			c.Writer.Status(),
-			c.Request.Method,
-			c.Request.RequestURI)
-		logger.Info(message)
+func (l accessLogger) headers(header http.Header) zapcore.ObjectMarshalerFunc {
+	return func(enc zapcore.ObjectEncoder) error {
+		for _, name := range l.config.Headers {
+			value := header.Get(name)
+			if value == "" {
+				continue
+			}
+			if l.redactHeaders[http.CanonicalHeaderKey(name)] {
+				value = redacted
+			}
+			enc.AddString(name, value)
+		}
+		return nil
 	}
 }
@@ -0,0 +1,106 @@
+package logging
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// logRequest serves the request through the access logger and returns the logged entry.
+func logRequest(t *testing.T, config Config, req *http.Request, status int) observer.LoggedEntry {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	router := gin.New()
+	router.Use(AccessLogger(zap.New(core), config))
+	router.GET("/person", func(c *gin.Context) {
+		c.Status(status)
+	})
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("got %d log entries, want 1", len(entries))
+	}
+	return entries[0]
+}
+
+func TestAccessLoggerRedactsQuery(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		query  string
+		want   string
+	}{
+		{name: "no sensitive params", query: "name=Ivan&page=1", want: "name=Ivan&page=1"},
+		{name: "default params", query: "apikey=secret&name=Ivan", want: "apikey=REDACTED&name=Ivan"},
+		{name: "case insensitive", query: "Access_Token=secret&Password=secret", want: "Access_Token=REDACTED&Password=REDACTED"},
+		{name: "configured params", config: Config{RedactQuery: []string{"Surname"}}, query: "surname=Petrov&token=secret", want: "surname=REDACTED&token=REDACTED"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := logRequest(t, tt.config, httptest.NewRequest(http.MethodGet, "/person?"+tt.query, nil), http.StatusOK)
+			if got := entry.ContextMap()["query"]; got != tt.want {
+				t.Errorf("query = %v, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAccessLoggerRedactsHeaders(t *testing.T) {
+	config := Config{
+		Headers:       []string{"Authorization", "x-api-key", "User-Agent", "X-Session", "X-Missing"},
+		RedactHeaders: []string{"x-session"},
+	}
+	req := httptest.NewRequest(http.MethodGet, "/person", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	req.Header.Set("X-API-Key", "tj_secret")
+	req.Header.Set("User-Agent", "test")
+	req.Header.Set("X-Session", "session")
+
+	entry := logRequest(t, config, req, http.StatusOK)
+
+	headers, ok := entry.ContextMap()["headers"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("headers = %v, want an object", entry.ContextMap()["headers"])
+	}
+	want := map[string]string{
+		"Authorization": redacted,
+		"x-api-key":     redacted,
+		"User-Agent":    "test",
+		"X-Session":     redacted,
+	}
+	if len(headers) != len(want) {
+		t.Errorf("headers = %v, want %v", headers, want)
+	}
+	for name, value := range want {
+		if headers[name] != value {
+			t.Errorf("header %s = %v, want %q", name, headers[name], value)
+		}
+	}
+}
+
+func TestAccessLoggerLevel(t *testing.T) {
+	tests := []struct {
+		status int
+		want   zapcore.Level
+	}{
+		{status: http.StatusOK, want: zapcore.InfoLevel},
+		{status: http.StatusNotFound, want: zapcore.WarnLevel},
+		{status: http.StatusInternalServerError, want: zapcore.ErrorLevel},
+	}
+
+	for _, tt := range tests {
+		entry := logRequest(t, Config{}, httptest.NewRequest(http.MethodGet, "/person", nil), tt.status)
+		if entry.Level != tt.want {
+			t.Errorf("status %d: level = %v, want %v", tt.status, entry.Level, tt.want)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package logging
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/maxik12233/task-junior/pkg/api"
+	"github.com/maxik12233/task-junior/pkg/logger"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// RequestID takes the id of the request from the X-Request-ID header or generates
+// it, and echoes it in the response. The request context gets the logger with
+// the request id and the trace id, so all logs of the request can be correlated.
+func RequestID(l *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		fields := []zap.Field{zap.String(api.RequestIdKey, api.RequestId(c))}
+		if span := trace.SpanContextFromContext(ctx); span.HasTraceID() {
+			fields = append(fields, zap.String("trace_id", span.TraceID().String()))
+		}
+
+		c.Request = c.Request.WithContext(logger.WithContext(ctx, l.With(fields...)))
+
+		c.Next()
+	}
+}
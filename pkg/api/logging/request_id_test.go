@@ -0,0 +1,116 @@
+package logging
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	app "github.com/maxik12233/task-junior"
+	"github.com/maxik12233/task-junior/pkg/api"
+	"github.com/maxik12233/task-junior/pkg/logger"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	generated := regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+	tests := []struct {
+		name          string
+		requestId     string
+		traceparent   string
+		fail          bool
+		wantRequestId string
+		wantTraceId   string
+	}{
+		{name: "request id of the header", requestId: "req-1", wantRequestId: "req-1"},
+		{name: "generated request id"},
+		{name: "malformed request id", requestId: "req 1\nlevel=error"},
+		{name: "continued trace", requestId: "req-1", traceparent: traceparent, wantRequestId: "req-1", wantTraceId: "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{name: "error envelope", requestId: "req-1", fail: true, wantRequestId: "req-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.DebugLevel)
+			router := gin.New()
+			router.Use(otelgin.Middleware("test",
+				otelgin.WithTracerProvider(sdktrace.NewTracerProvider()),
+				otelgin.WithPropagators(propagation.TraceContext{}),
+			))
+			router.Use(RequestID(zap.New(core)))
+			router.Use(AccessLogger(zap.NewNop(), Config{}))
+			router.GET("/person", func(c *gin.Context) {
+				// Service, repository and sdk logs take the logger of the context
+				logger.FromContext(c.Request.Context(), zap.NewNop()).Info("Handler")
+				if tt.fail {
+					api.AbortWithError(c, app.ErrNotFound)
+					return
+				}
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/person", nil)
+			if tt.requestId != "" {
+				req.Header.Set(api.RequestIdHeader, tt.requestId)
+			}
+			if tt.traceparent != "" {
+				req.Header.Set("traceparent", tt.traceparent)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			requestId := w.Header().Get(api.RequestIdHeader)
+			if tt.wantRequestId != "" && requestId != tt.wantRequestId {
+				t.Errorf("%s = %q, want %q", api.RequestIdHeader, requestId, tt.wantRequestId)
+			}
+			if tt.wantRequestId == "" && !generated.MatchString(requestId) {
+				t.Errorf("%s = %q, want a generated id", api.RequestIdHeader, requestId)
+			}
+
+			// Both the handler and the access log lines carry the ids
+			entries := logs.All()
+			if len(entries) != 2 {
+				t.Fatalf("got %d log entries, want 2", len(entries))
+			}
+			var traceId string
+			for _, entry := range entries {
+				fields := entry.ContextMap()
+				if fields[api.RequestIdKey] != requestId {
+					t.Errorf("%q log request_id = %v, want %q", entry.Message, fields[api.RequestIdKey], requestId)
+				}
+				id, _ := fields["trace_id"].(string)
+				if len(id) != 32 {
+					t.Errorf("%q log trace_id = %v, want the id of the span", entry.Message, fields["trace_id"])
+				}
+				if traceId != "" && id != traceId {
+					t.Errorf("log lines have trace ids %q and %q", traceId, id)
+				}
+				traceId = id
+			}
+			if tt.wantTraceId != "" && traceId != tt.wantTraceId {
+				t.Errorf("trace_id = %q, want %q of the traceparent", traceId, tt.wantTraceId)
+			}
+
+			if !tt.fail {
+				return
+			}
+			var problem api.ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("bad problem body %s: %v", w.Body, err)
+			}
+			if problem.RequestId != requestId {
+				t.Errorf("problem request_id = %q, want %q", problem.RequestId, requestId)
+			}
+		})
+	}
+}
@@ -2,10 +2,10 @@ package paginate
 
 import (
 	"context"
-	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	app "github.com/maxik12233/task-junior"
 	"github.com/maxik12233/task-junior/pkg/api"
 )
 
@@ -30,11 +30,7 @@ func Middleware(defaultPage int, defaultPerPage int) gin.HandlerFunc {
 			var err error
 			page, err = strconv.Atoi(pageInQuery)
 			if err != nil {
-				errResponse := api.ErrorResponse{
-					Message: "bad pagination values",
-				}
-				c.Writer.WriteHeader(http.StatusBadRequest)
-				c.Writer.Write(errResponse.Marshal())
+				api.AbortWithError(c, app.WrapE(app.ErrInvalidParamType, "bad pagination values"))
 				return
 			}
 		}
@@ -46,11 +42,7 @@ func Middleware(defaultPage int, defaultPerPage int) gin.HandlerFunc {
 			var err error
 			perPage, err = strconv.Atoi(perPageInQuery)
 			if err != nil {
-				errResponse := api.ErrorResponse{
-					Message: "per_page value is too large",
-				}
-				c.Writer.WriteHeader(http.StatusBadRequest)
-				c.Writer.Write(errResponse.Marshal())
+				api.AbortWithError(c, app.WrapE(app.ErrInvalidParamType, "bad per_page value"))
 				return
 			}
 		}
@@ -0,0 +1,166 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	app "github.com/maxik12233/task-junior"
+	"github.com/maxik12233/task-junior/pkg/api"
+	"github.com/maxik12233/task-junior/pkg/api/auth"
+	"github.com/maxik12233/task-junior/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	LimitHeader     = "RateLimit-Limit"
+	RemainingHeader = "RateLimit-Remaining"
+	ResetHeader     = "RateLimit-Reset"
+	PolicyHeader    = "RateLimit-Policy"
+)
+
+// Limit allows Requests per Period with bursts up to Burst requests.
+// Zero Burst means Requests.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+func (l Limit) burst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return l.Requests
+}
+
+func (l Limit) valid() bool {
+	return l.Requests > 0 && l.Period > 0
+}
+
+// Config holds the default limit and the limits of the routes keyed by
+// method and route template, e.g. "POST /person". The routes with their own
+// limit have their own buckets, the others share the default one.
+// IP limits all requests of the client IP before authentication, zero IP is no limit.
+type Config struct {
+	Default Limit
+	Routes  map[string]Limit
+	IP      Limit
+}
+
+// ClientKey identifies the client by the authenticated subject, which is
+// the JWT subject or the API key, and falls back to the client IP.
+func ClientKey(c *gin.Context) string {
+	if subject := auth.Subject(c.Request.Context()); subject != "" {
+		return "subject:" + subject
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// Limiter limits the request rate of each client with token buckets.
+type Limiter struct {
+	store  Store
+	config Config
+	logger *zap.Logger
+}
+
+// New creates the limiter. If store is nil, buckets are kept in memory.
+func New(store Store, config Config, logger *zap.Logger) (*Limiter, error) {
+	if store == nil {
+		store = NewMemoryStore()
+	}
+	if !config.Default.valid() {
+		return nil, fmt.Errorf("default rate limit must have positive requests and period")
+	}
+
+	routes := make(map[string]Limit, len(config.Routes))
+	for route, limit := range config.Routes {
+		if !limit.valid() {
+			return nil, fmt.Errorf("rate limit of %q must have positive requests and period", route)
+		}
+		routes[routeKey(route)] = limit
+	}
+	config.Routes = routes
+
+	if config.IP != (Limit{}) && !config.IP.valid() {
+		return nil, fmt.Errorf("ip rate limit must have positive requests and period")
+	}
+
+	return &Limiter{
+		store:  store,
+		config: config,
+		logger: logger,
+	}, nil
+}
+
+// routeKey normalizes "post /person" to "POST /person", as config keys may be lowercased.
+func routeKey(route string) string {
+	method, path, _ := strings.Cut(strings.TrimSpace(route), " ")
+	return strings.ToUpper(method) + " " + strings.TrimSpace(path)
+}
+
+// Middleware takes a token of the client for the request and responds with
+// 429 Too Many Requests when there is none. Store failures let the request through.
+func (l *Limiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.Request.Method + " " + c.FullPath()
+		limit, ok := l.config.Routes[route]
+		if !ok {
+			limit = l.config.Default
+			route = "default"
+		}
+
+		l.take(c, route, ClientKey(c), limit)
+	}
+}
+
+// IPMiddleware limits the requests of the client IP by the IP limit. It goes
+// before authentication, so requests with bad credentials are limited too.
+func (l *Limiter) IPMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if l.config.IP == (Limit{}) {
+			c.Next()
+			return
+		}
+
+		l.take(c, "ip", "ip:"+c.ClientIP(), l.config.IP)
+	}
+}
+
+// take takes a token of the key for the route and aborts the request when there is none.
+func (l *Limiter) take(c *gin.Context, route, key string, limit Limit) {
+	result, err := l.store.Take(c.Request.Context(), route+"|"+key, limit)
+	if err != nil {
+		logger.FromContext(c.Request.Context(), l.logger).Error("Failed to take rate limit token", zap.String("key", key), zap.Error(err))
+		c.Next()
+		return
+	}
+
+	c.Header(LimitHeader, strconv.Itoa(limit.burst()))
+	c.Header(RemainingHeader, strconv.Itoa(result.Remaining))
+	c.Header(ResetHeader, strconv.Itoa(ceilSeconds(result.Reset)))
+	c.Header(PolicyHeader, fmt.Sprintf("%d;w=%d", limit.Requests, ceilSeconds(limit.Period)))
+
+	if !result.Allowed {
+		logger.FromContext(c.Request.Context(), l.logger).Warn("Rate limit exceeded",
+			zap.String("key", key),
+			zap.String("route", route),
+		)
+		c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+		api.AbortWithError(c, app.WrapE(app.ErrTooManyRequests, "rate limit exceeded"))
+		return
+	}
+
+	c.Next()
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,204 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func TestRouteKey(t *testing.T) {
+	tests := []struct {
+		route string
+		want  string
+	}{
+		{route: "POST /person", want: "POST /person"},
+		{route: "post /person", want: "POST /person"},
+		{route: "  get   /person/export ", want: "GET /person/export"},
+		{route: "DELETE", want: "DELETE "},
+	}
+
+	for _, tt := range tests {
+		if got := routeKey(tt.route); got != tt.want {
+			t.Errorf("routeKey(%q) = %q, want %q", tt.route, got, tt.want)
+		}
+	}
+}
+
+func TestMemoryStoreTake(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   Limit
+		takes   int
+		allowed int
+	}{
+		{name: "burst defaults to requests", limit: Limit{Requests: 3, Period: time.Hour}, takes: 5, allowed: 3},
+		{name: "burst", limit: Limit{Requests: 10, Period: time.Hour, Burst: 2}, takes: 5, allowed: 2},
+		{name: "single", limit: Limit{Requests: 1, Period: time.Hour}, takes: 2, allowed: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStore()
+
+			allowed := 0
+			var last Result
+			for i := 0; i < tt.takes; i++ {
+				result, err := store.Take(context.Background(), "key", tt.limit)
+				if err != nil {
+					t.Fatalf("Take() error = %v", err)
+				}
+				if result.Allowed {
+					allowed++
+				}
+				last = result
+			}
+
+			if allowed != tt.allowed {
+				t.Errorf("allowed %d of %d takes, want %d", allowed, tt.takes, tt.allowed)
+			}
+			if last.Allowed || last.RetryAfter <= 0 || last.Remaining != 0 {
+				t.Errorf("last take = %+v, want denied with retry after", last)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreTakeRefills(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Requests: 100, Period: time.Second, Burst: 1}
+
+	if result, _ := store.Take(context.Background(), "key", limit); !result.Allowed {
+		t.Fatal("first take is denied")
+	}
+	if result, _ := store.Take(context.Background(), "key", limit); result.Allowed {
+		t.Fatal("take over the burst is allowed")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if result, _ := store.Take(context.Background(), "key", limit); !result.Allowed {
+		t.Error("take after the refill is denied")
+	}
+}
+
+func TestMemoryStoreKeysAreSeparate(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Requests: 1, Period: time.Hour}
+
+	for _, key := range []string{"a", "b"} {
+		if result, _ := store.Take(context.Background(), key, limit); !result.Allowed {
+			t.Errorf("first take of %q is denied", key)
+		}
+	}
+}
+
+func TestIPMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name  string
+		ip    Limit
+		codes []int
+	}{
+		{name: "limited", ip: Limit{Requests: 1, Period: time.Hour}, codes: []int{http.StatusOK, http.StatusTooManyRequests}},
+		{name: "no limit", codes: []int{http.StatusOK, http.StatusOK}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter, err := New(nil, Config{Default: Limit{Requests: 100, Period: time.Hour}, IP: tt.ip}, zap.NewNop())
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			router := gin.New()
+			router.Use(limiter.IPMiddleware())
+			router.GET("/", func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			for i, want := range tt.codes {
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+				if w.Code != want {
+					t.Errorf("request %d status = %d, want %d", i, w.Code, want)
+				}
+			}
+		})
+	}
+}
+
+func TestIPMiddlewareForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// httptest requests come from 192.0.2.1
+	tests := []struct {
+		name    string
+		proxies []string
+		codes   []int
+	}{
+		{name: "spoofed header of untrusted client", codes: []int{http.StatusOK, http.StatusTooManyRequests, http.StatusTooManyRequests}},
+		{name: "header of trusted proxy", proxies: []string{"192.0.2.0/24"}, codes: []int{http.StatusOK, http.StatusOK, http.StatusOK}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter, err := New(nil, Config{
+				Default: Limit{Requests: 100, Period: time.Hour},
+				IP:      Limit{Requests: 1, Period: time.Hour},
+			}, zap.NewNop())
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			router := gin.New()
+			if err := router.SetTrustedProxies(tt.proxies); err != nil {
+				t.Fatalf("SetTrustedProxies() error = %v", err)
+			}
+			router.Use(limiter.IPMiddleware())
+			router.GET("/", func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			forwardedFor := []string{"203.0.113.1", "203.0.113.2", "203.0.113.3"}
+			for i, want := range tt.codes {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.Header.Set("X-Forwarded-For", forwardedFor[i])
+				req.Header.Set("X-Real-IP", forwardedFor[i])
+
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+				if w.Code != want {
+					t.Errorf("request %d status = %d, want %d", i, w.Code, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewRejectsBadLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{name: "no default", config: Config{}},
+		{name: "bad route", config: Config{
+			Default: Limit{Requests: 1, Period: time.Second},
+			Routes:  map[string]Limit{"POST /person": {Requests: 1}},
+		}},
+		{name: "bad ip", config: Config{
+			Default: Limit{Requests: 1, Period: time.Second},
+			IP:      Limit{Period: time.Second},
+		}},
+	}
+
+	for _, tt := range tests {
+		if _, err := New(nil, tt.config, zap.NewNop()); err == nil {
+			t.Errorf("%s: New() error = nil", tt.name)
+		}
+	}
+}
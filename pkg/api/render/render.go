@@ -0,0 +1,82 @@
+package render
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	ginrender "github.com/gin-gonic/gin/render"
+	app "github.com/maxik12233/task-junior"
+	"github.com/maxik12233/task-junior/pkg/api"
+)
+
+const (
+	MIMECSV = "text/csv"
+)
+
+// Offered content types in order of preference. The first one is used
+// when the client doesn't send Accept header.
+var offered = []string{
+	binding.MIMEJSON,
+	binding.MIMEXML,
+	binding.MIMEXML2,
+	binding.MIMEMSGPACK,
+	binding.MIMEMSGPACK2,
+	MIMECSV,
+}
+
+// CSVMarshaler is implemented by list responses which can be rendered as csv.
+type CSVMarshaler interface {
+	MarshalCSV() (header []string, rows [][]string)
+}
+
+// Render writes obj in the format negotiated by the request's Accept header.
+// Responds with 406 problem if none of the accepted formats is supported.
+// Errors are not rendered here, they are always written by api.AbortWithError.
+func Render(c *gin.Context, code int, obj interface{}) {
+	switch c.NegotiateFormat(offered...) {
+	case binding.MIMEJSON:
+		c.JSON(code, obj)
+	case binding.MIMEXML, binding.MIMEXML2:
+		c.XML(code, obj)
+	case binding.MIMEMSGPACK, binding.MIMEMSGPACK2:
+		c.Render(code, ginrender.MsgPack{Data: obj})
+	case MIMECSV:
+		marshaler, ok := obj.(CSVMarshaler)
+		if !ok {
+			notAcceptable(c)
+			return
+		}
+		c.Render(code, csvRender{marshaler: marshaler})
+	default:
+		notAcceptable(c)
+	}
+}
+
+func notAcceptable(c *gin.Context) {
+	api.AbortWithError(c, app.WrapE(app.ErrNotAcceptable, "supported types: "+strings.Join(offered, ", ")))
+}
+
+type csvRender struct {
+	marshaler CSVMarshaler
+}
+
+func (r csvRender) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+
+	header, rows := r.marshaler.MarshalCSV()
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r csvRender) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", MIMECSV+"; charset=utf-8")
+}
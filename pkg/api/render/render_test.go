@@ -0,0 +1,147 @@
+package render
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/maxik12233/task-junior/pkg/api"
+)
+
+type person struct {
+	Name    string `json:"name" xml:"name"`
+	Surname string `json:"surname" xml:"surname"`
+}
+
+type persons []person
+
+func (p persons) MarshalCSV() ([]string, [][]string) {
+	rows := make([][]string, len(p))
+	for i, v := range p {
+		rows[i] = []string{v.Name, v.Surname}
+	}
+	return []string{"name", "surname"}, rows
+}
+
+func TestRender(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	list := persons{{Name: "Ivan", Surname: "Ivanov"}, {Name: "Petr", Surname: "Petrov, Jr."}}
+
+	tests := []struct {
+		name            string
+		accept          string
+		obj             interface{}
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:            "no accept header",
+			obj:             list[0],
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json; charset=utf-8",
+			wantBody:        `{"name":"Ivan","surname":"Ivanov"}`,
+		},
+		{
+			name:            "any type",
+			accept:          "*/*",
+			obj:             list[0],
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json; charset=utf-8",
+			wantBody:        `{"name":"Ivan","surname":"Ivanov"}`,
+		},
+		{
+			name:            "json",
+			accept:          "application/json",
+			obj:             list[0],
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json; charset=utf-8",
+			wantBody:        `{"name":"Ivan","surname":"Ivanov"}`,
+		},
+		{
+			name:            "xml",
+			accept:          "application/xml",
+			obj:             list[0],
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/xml; charset=utf-8",
+			wantBody:        "<person><name>Ivan</name><surname>Ivanov</surname></person>",
+		},
+		{
+			name:            "text xml",
+			accept:          "text/xml",
+			obj:             list[0],
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/xml; charset=utf-8",
+			wantBody:        "<person><name>Ivan</name><surname>Ivanov</surname></person>",
+		},
+		{
+			name:            "msgpack",
+			accept:          "application/x-msgpack",
+			obj:             list[0],
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/msgpack; charset=utf-8",
+		},
+		{
+			name:            "first supported type of the list",
+			accept:          "image/png, text/csv;q=0.9, application/json;q=0.8",
+			obj:             list,
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/csv; charset=utf-8",
+			wantBody:        "name,surname\nIvan,Ivanov\nPetr,\"Petrov, Jr.\"\n",
+		},
+		{
+			name:       "csv of a single object",
+			accept:     "text/csv",
+			obj:        list[0],
+			wantStatus: http.StatusNotAcceptable,
+		},
+		{
+			name:       "unsupported type",
+			accept:     "image/png",
+			obj:        list[0],
+			wantStatus: http.StatusNotAcceptable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/", func(c *gin.Context) {
+				Render(c, http.StatusOK, tt.obj)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus == http.StatusNotAcceptable {
+				if got := w.Header().Get("Content-Type"); got != api.ProblemContentType {
+					t.Errorf("Content-Type = %q, want %q", got, api.ProblemContentType)
+				}
+				if !strings.Contains(w.Body.String(), "text/csv") {
+					t.Errorf("body %s doesn't list the supported types", w.Body)
+				}
+				return
+			}
+
+			if got := w.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantContentType)
+			}
+			if w.Body.Len() == 0 {
+				t.Error("body is empty")
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", w.Body, tt.wantBody)
+			}
+		})
+	}
+}
@@ -2,10 +2,10 @@ package sort
 
 import (
 	"context"
-	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	app "github.com/maxik12233/task-junior"
 	"github.com/maxik12233/task-junior/pkg/api"
 )
 
@@ -20,26 +20,31 @@ type Options struct {
 	Order string
 }
 
-func Middleware(defaultSortField, defaultSortOrder string) gin.HandlerFunc {
+// Middleware reads the sort options of the request. Only the given fields may be
+// sorted by, as the field is put into the query as is.
+func Middleware(defaultSortField, defaultSortOrder string, fields ...string) gin.HandlerFunc {
+	allowed := make(map[string]bool, len(fields))
+	for _, v := range fields {
+		allowed[v] = true
+	}
+
 	return func(c *gin.Context) {
 		sortBy := c.Request.URL.Query().Get("sort_by")
 		sortOrder := c.Request.URL.Query().Get("sort_order")
 
 		if sortBy == "" {
 			sortBy = defaultSortField
+		} else if !allowed[sortBy] {
+			api.AbortWithError(c, app.WrapE(app.ErrInvalidParamType, "sort_by must be one of: "+strings.Join(fields, ", ")))
+			return
 		}
 
 		if sortOrder == "" {
 			sortOrder = defaultSortOrder
 		} else {
-			upperSortOrder := strings.ToUpper(sortOrder)
-			if upperSortOrder != ASC && upperSortOrder != DESC {
-				c.Writer.WriteHeader(http.StatusBadRequest)
-				err := api.ErrorResponse{
-					Message: "collation must be asc or desc",
-					Details: nil,
-				}
-				c.Writer.Write(err.Marshal())
+			sortOrder = strings.ToLower(sortOrder)
+			if sortOrder != ASC && sortOrder != DESC {
+				api.AbortWithError(c, app.WrapE(app.ErrInvalidParamType, "collation must be asc or desc"))
 				return
 			}
 		}
@@ -0,0 +1,58 @@
+package sort
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	app "github.com/maxik12233/task-junior"
+	"github.com/maxik12233/task-junior/pkg/api"
+)
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		want       Options
+	}{
+		{name: "defaults", wantStatus: http.StatusOK, want: Options{Field: "name", Order: ASC}},
+		{name: "field and order", query: "?sort_by=surname&sort_order=DESC", wantStatus: http.StatusOK, want: Options{Field: "surname", Order: DESC}},
+		{name: "unknown field", query: "?sort_by=age", wantStatus: http.StatusBadRequest},
+		{name: "unknown order", query: "?sort_order=up", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Options
+			router := gin.New()
+			router.GET("/", Middleware("name", ASC, "name", "surname"), func(c *gin.Context) {
+				got, _ = c.Request.Context().Value(OptionsContextKey).(Options)
+			})
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+tt.query, nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus == http.StatusOK {
+				if got != tt.want {
+					t.Errorf("options = %+v, want %+v", got, tt.want)
+				}
+				return
+			}
+
+			var problem api.ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil || w.Header().Get("Content-Type") != api.ProblemContentType {
+				t.Fatalf("response is not a problem: %s", w.Body)
+			}
+			if problem.Code != app.ErrorCode(app.ErrInvalidParamType) || problem.RequestId == "" {
+				t.Errorf("problem = %+v, want code %d with the request id", problem, app.ErrorCode(app.ErrInvalidParamType))
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package validation
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/ru"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	en_translations "github.com/go-playground/validator/v10/translations/en"
+	ru_translations "github.com/go-playground/validator/v10/translations/ru"
+	"github.com/maxik12233/task-junior/pkg/api"
+)
+
+const (
+	DefaultLocale = "en"
+)
+
+// FieldError describes a single failed validation rule of a request field.
+type FieldError struct {
+	Field   string `json:"field" xml:"field"`
+	Rule    string `json:"rule" xml:"rule"`
+	Param   string `json:"param,omitempty" xml:"param,omitempty"`
+	Message string `json:"message" xml:"message"`
+}
+
+// Validator wraps validator.Validate, which caches struct metadata and
+// therefore should be shared, with translations of its error messages.
+type Validator struct {
+	validate   *validator.Validate
+	translator *ut.UniversalTranslator
+}
+
+func New() (*Validator, error) {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(jsonFieldName)
+
+	enLocale := en.New()
+	translator := ut.New(enLocale, enLocale, ru.New())
+
+	enTrans, _ := translator.GetTranslator("en")
+	if err := en_translations.RegisterDefaultTranslations(validate, enTrans); err != nil {
+		return nil, err
+	}
+	ruTrans, _ := translator.GetTranslator("ru")
+	if err := ru_translations.RegisterDefaultTranslations(validate, ruTrans); err != nil {
+		return nil, err
+	}
+
+	return &Validator{
+		validate:   validate,
+		translator: translator,
+	}, nil
+}
+
+// Messages maps locale to the error message template of a rule.
+// {0} is replaced with the field name and {1} with the rule param.
+type Messages map[string]string
+
+// RegisterRule adds a custom validation rule with its translated error messages.
+func (v *Validator) RegisterRule(tag string, fn validator.Func, messages Messages) error {
+	if err := v.validate.RegisterValidation(tag, fn); err != nil {
+		return err
+	}
+
+	for locale, text := range messages {
+		trans, found := v.translator.GetTranslator(locale)
+		if !found {
+			continue
+		}
+
+		text := text
+		err := v.validate.RegisterTranslation(tag, trans,
+			func(trans ut.Translator) error {
+				return trans.Add(tag, text, true)
+			},
+			func(trans ut.Translator, fe validator.FieldError) string {
+				message, err := trans.T(tag, fe.Field(), fe.Param())
+				if err != nil {
+					return fe.Error()
+				}
+				return message
+			})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (v *Validator) Struct(s interface{}) error {
+	return v.validate.Struct(s)
+}
+
+// Var validates a single value against the rules in the tag.
+func (v *Validator) Var(field interface{}, tag string) error {
+	return v.validate.Var(field, tag)
+}
+
+// FieldErrors translates validation errors into the language preferred by the
+// Accept-Language header value. Returns nil if err is not a validation error.
+func (v *Validator) FieldErrors(err error, acceptLanguage string) []FieldError {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return nil
+	}
+
+	trans, _ := v.translator.FindTranslator(api.ParseAcceptLanguage(acceptLanguage)...)
+
+	fieldErrors := make([]FieldError, len(validationErrors))
+	for i, fe := range validationErrors {
+		fieldErrors[i] = FieldError{
+			Field:   fieldPath(fe),
+			Rule:    fe.Tag(),
+			Param:   fe.Param(),
+			Message: fe.Translate(trans),
+		}
+	}
+
+	return fieldErrors
+}
+
+// Messages returns the translated messages of the validation error in the default locale.
+func (v *Validator) Messages(err error) []string {
+	fieldErrors := v.FieldErrors(err, DefaultLocale)
+	if fieldErrors == nil {
+		return []string{err.Error()}
+	}
+
+	messages := make([]string, len(fieldErrors))
+	for i, fe := range fieldErrors {
+		messages[i] = fe.Message
+	}
+	return messages
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// fieldPath strips the top level struct name from the field namespace.
+func fieldPath(fe validator.FieldError) string {
+	_, path, ok := strings.Cut(fe.Namespace(), ".")
+	if !ok {
+		return fe.Field()
+	}
+	return path
+}
@@ -0,0 +1,113 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type address struct {
+	City string `json:"city" validate:"required"`
+}
+
+type request struct {
+	Name    string   `json:"name" validate:"required,min=2"`
+	Age     int      `json:"age,omitempty" validate:"lte=200"`
+	Tags    []string `validate:"max=1"`
+	Address address  `json:"address"`
+}
+
+func TestFieldErrors(t *testing.T) {
+	v, err := New()
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	err = v.Struct(request{Name: "I", Age: 201, Tags: []string{"a", "b"}})
+
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		want           []FieldError
+	}{
+		{
+			name: "default locale",
+			want: []FieldError{
+				{Field: "name", Rule: "min", Param: "2", Message: "name must be at least 2 characters in length"},
+				{Field: "age", Rule: "lte", Param: "200", Message: "age must be 200 or less"},
+				{Field: "Tags", Rule: "max", Param: "1", Message: "Tags must contain at maximum 1 item"},
+				{Field: "address.city", Rule: "required", Message: "city is a required field"},
+			},
+		},
+		{
+			name:           "preferred locale",
+			acceptLanguage: "ru-RU,ru;q=0.9,en;q=0.8",
+			want: []FieldError{
+				{Field: "name", Rule: "min", Param: "2", Message: "name должен содержать минимум 2 символа"},
+				{Field: "age", Rule: "lte", Param: "200", Message: "age должен быть менее или равен 200"},
+				{Field: "Tags", Rule: "max", Param: "1", Message: "Tags должен содержать максимум 1 элемент"},
+				{Field: "address.city", Rule: "required", Message: "city обязательное поле"},
+			},
+		},
+		{
+			name:           "unsupported locale",
+			acceptLanguage: "de",
+			want: []FieldError{
+				{Field: "name", Rule: "min", Param: "2", Message: "name must be at least 2 characters in length"},
+				{Field: "age", Rule: "lte", Param: "200", Message: "age must be 200 or less"},
+				{Field: "Tags", Rule: "max", Param: "1", Message: "Tags must contain at maximum 1 item"},
+				{Field: "address.city", Rule: "required", Message: "city is a required field"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := v.FieldErrors(err, tt.acceptLanguage)
+			if len(got) != len(tt.want) {
+				t.Fatalf("FieldErrors() = %+v, want %+v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("FieldErrors()[%d] = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRegisterRule(t *testing.T) {
+	v, err := New()
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	even := func(fl validator.FieldLevel) bool {
+		return fl.Field().Int()%2 == 0
+	}
+	if err := v.RegisterRule("even", even, Messages{"en": "{0} must be even", "ru": "{0} должно быть чётным"}); err != nil {
+		t.Fatalf("RegisterRule() error = %v", err)
+	}
+
+	type evenRequest struct {
+		Count int `json:"count" validate:"even"`
+	}
+	if err := v.Struct(evenRequest{Count: 2}); err != nil {
+		t.Errorf("Struct() of the valid request error = %v", err)
+	}
+	err = v.Struct(evenRequest{Count: 3})
+	if got := v.FieldErrors(err, "ru"); len(got) != 1 || got[0] != (FieldError{Field: "count", Rule: "even", Message: "count должно быть чётным"}) {
+		t.Errorf("FieldErrors() = %+v", got)
+	}
+	if got := v.Messages(err); len(got) != 1 || got[0] != "count must be even" {
+		t.Errorf("Messages() = %q", got)
+	}
+
+	other := errors.New("bad json")
+	if got := v.FieldErrors(other, "en"); got != nil {
+		t.Errorf("FieldErrors() of not a validation error = %+v, want nil", got)
+	}
+	if got := v.Messages(other); len(got) != 1 || got[0] != "bad json" {
+		t.Errorf("Messages() of not a validation error = %q", got)
+	}
+}
@@ -0,0 +1,91 @@
+package countries
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"strings"
+)
+
+// iso3166.csv is generated from the iso-codes project ISO 3166-1 dataset
+// and its russian translation. Regions and continents follow the UN M49 geoscheme.
+//
+//go:embed iso3166.csv
+var iso3166 string
+
+const (
+	LocaleEn = "en"
+	LocaleRu = "ru"
+)
+
+type Country struct {
+	Alpha2    string
+	Alpha3    string
+	Numeric   string
+	Name      string
+	NameRu    string
+	Region    string
+	Continent string
+}
+
+// LocalizedName returns the country name in the locale, english by default.
+func (c Country) LocalizedName(locale string) string {
+	if locale == LocaleRu && c.NameRu != "" {
+		return c.NameRu
+	}
+	return c.Name
+}
+
+var (
+	all     []Country
+	byAlpha map[string]Country
+)
+
+func init() {
+	records, err := csv.NewReader(strings.NewReader(iso3166)).ReadAll()
+	if err != nil {
+		panic("countries: bad embedded iso3166.csv: " + err.Error())
+	}
+
+	all = make([]Country, 0, len(records)-1)
+	byAlpha = make(map[string]Country, len(records)-1)
+	for _, v := range records[1:] {
+		country := Country{
+			Alpha2:    v[0],
+			Alpha3:    v[1],
+			Numeric:   v[2],
+			Name:      v[3],
+			NameRu:    v[4],
+			Region:    v[5],
+			Continent: v[6],
+		}
+		all = append(all, country)
+		byAlpha[country.Alpha2] = country
+	}
+}
+
+// Lookup finds the country by its ISO 3166-1 alpha-2 code, case insensitive.
+func Lookup(alpha2 string) (Country, bool) {
+	country, ok := byAlpha[strings.ToUpper(alpha2)]
+	return country, ok
+}
+
+func IsAlpha2(code string) bool {
+	_, ok := Lookup(code)
+	return ok
+}
+
+// Filter returns countries of the region and the continent, case insensitive.
+// Empty region or continent matches any.
+func Filter(region, continent string) []Country {
+	var result []Country
+	for _, v := range all {
+		if region != "" && !strings.EqualFold(v.Region, region) {
+			continue
+		}
+		if continent != "" && !strings.EqualFold(v.Continent, continent) {
+			continue
+		}
+		result = append(result, v)
+	}
+	return result
+}
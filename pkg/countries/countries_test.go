@@ -0,0 +1,93 @@
+package countries
+
+import "testing"
+
+func TestDataset(t *testing.T) {
+	if len(all) != 249 {
+		t.Errorf("got %d countries, want 249", len(all))
+	}
+	for _, v := range all {
+		if len(v.Alpha2) != 2 || len(v.Alpha3) != 3 || len(v.Numeric) != 3 {
+			t.Errorf("bad codes of %+v", v)
+		}
+		if v.Name == "" || v.NameRu == "" || v.Region == "" || v.Continent == "" {
+			t.Errorf("country %s has empty fields: %+v", v.Alpha2, v)
+		}
+	}
+	if len(byAlpha) != len(all) {
+		t.Errorf("got %d unique alpha-2 codes of %d countries", len(byAlpha), len(all))
+	}
+}
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		code   string
+		want   string
+		wantRu string
+		wantOk bool
+	}{
+		{code: "RU", want: "Russian Federation", wantRu: "Российская Федерация", wantOk: true},
+		{code: "de", want: "Germany", wantRu: "Германия", wantOk: true},
+		{code: "XX"},
+		{code: "RUS"},
+		{code: ""},
+	}
+
+	for _, tt := range tests {
+		country, ok := Lookup(tt.code)
+		if ok != tt.wantOk {
+			t.Errorf("Lookup(%q) found = %v, want %v", tt.code, ok, tt.wantOk)
+			continue
+		}
+		if IsAlpha2(tt.code) != tt.wantOk {
+			t.Errorf("IsAlpha2(%q) = %v, want %v", tt.code, !tt.wantOk, tt.wantOk)
+		}
+		if !ok {
+			continue
+		}
+		if got := country.LocalizedName(LocaleEn); got != tt.want {
+			t.Errorf("LocalizedName(en) of %q = %q, want %q", tt.code, got, tt.want)
+		}
+		if got := country.LocalizedName(LocaleRu); got != tt.wantRu {
+			t.Errorf("LocalizedName(ru) of %q = %q, want %q", tt.code, got, tt.wantRu)
+		}
+		if got := country.LocalizedName("de"); got != tt.want {
+			t.Errorf("LocalizedName(de) of %q = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	tests := []struct {
+		name      string
+		region    string
+		continent string
+		wantLen   int
+		wantCode  string
+	}{
+		{name: "all", wantLen: 249},
+		{name: "region", region: "central asia", wantLen: 5, wantCode: "KZ"},
+		{name: "continent", continent: "EUROPE", wantLen: 51, wantCode: "RU"},
+		{name: "region of the continent", region: "Western Europe", continent: "Europe", wantLen: 9, wantCode: "DE"},
+		{name: "region of another continent", region: "Western Europe", continent: "Asia"},
+		{name: "unknown region", region: "Atlantis"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Filter(tt.region, tt.continent)
+			if len(got) != tt.wantLen {
+				t.Errorf("Filter(%q, %q) = %d countries, want %d", tt.region, tt.continent, len(got), tt.wantLen)
+			}
+			if tt.wantCode == "" {
+				return
+			}
+			for _, v := range got {
+				if v.Alpha2 == tt.wantCode {
+					return
+				}
+			}
+			t.Errorf("Filter(%q, %q) has no %s", tt.region, tt.continent, tt.wantCode)
+		})
+	}
+}
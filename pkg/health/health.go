@@ -0,0 +1,145 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/maxik12233/task-junior/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	LivenessURL  = "/livez"
+	ReadinessURL = "/readyz"
+
+	StatusOK       = "ok"
+	StatusFail     = "fail"
+	StatusDraining = "draining"
+
+	defaultTimeout = 2 * time.Second
+)
+
+// Check is a readiness check of a dependency. Zero Timeout means 2 seconds.
+type Check struct {
+	Name    string
+	Timeout time.Duration
+	// Optional checks are reported, but don't fail the readiness.
+	Optional bool
+	Func     func(ctx context.Context) error
+}
+
+type CheckResult struct {
+	Status     string  `json:"status"`
+	DurationMs float64 `json:"duration_ms"`
+	Error      string  `json:"error,omitempty"`
+	Optional   bool    `json:"optional,omitempty"`
+}
+
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// Checker serves the liveness and readiness probes.
+type Checker struct {
+	logger   *zap.Logger
+	checks   []Check
+	draining atomic.Bool
+}
+
+func NewChecker(logger *zap.Logger, checks ...Check) *Checker {
+	return &Checker{
+		logger: logger,
+		checks: checks,
+	}
+}
+
+func (h *Checker) Register(router *gin.Engine) {
+	router.GET(LivenessURL, h.Livez)
+	router.GET(ReadinessURL, h.Readyz)
+}
+
+// Livez reports that the process is serving requests. It doesn't check the
+// dependencies, so their outage doesn't get the service restarted.
+func (h *Checker) Livez(c *gin.Context) {
+	c.JSON(http.StatusOK, Report{Status: StatusOK})
+}
+
+// Drain makes the service unready, so it gets no new requests while it is shutting down.
+func (h *Checker) Drain() {
+	h.draining.Store(true)
+}
+
+// Readyz runs the checks concurrently and responds with 503 Service Unavailable
+// if any of the required ones has failed or the service is draining.
+func (h *Checker) Readyz(c *gin.Context) {
+	if h.draining.Load() {
+		c.JSON(http.StatusServiceUnavailable, Report{Status: StatusDraining})
+		return
+	}
+
+	report := h.Run(c.Request.Context())
+
+	code := http.StatusOK
+	if report.Status != StatusOK {
+		code = http.StatusServiceUnavailable
+	}
+	c.JSON(code, report)
+}
+
+func (h *Checker) Run(ctx context.Context) Report {
+	report := Report{
+		Status: StatusOK,
+		Checks: make(map[string]CheckResult, len(h.checks)),
+	}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, check := range h.checks {
+		wg.Add(1)
+		go func(check Check) {
+			defer wg.Done()
+			result := h.run(ctx, check)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[check.Name] = result
+			if result.Status != StatusOK && !check.Optional {
+				report.Status = StatusFail
+			}
+		}(check)
+	}
+	wg.Wait()
+
+	return report
+}
+
+func (h *Checker) run(ctx context.Context, check Check) CheckResult {
+	timeout := check.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Func(ctx)
+	result := CheckResult{
+		Status:     StatusOK,
+		DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+		Optional:   check.Optional,
+	}
+	if err != nil {
+		logger.FromContext(ctx, h.logger).Warn("Readiness check failed", zap.String("check", check.Name), zap.Error(err))
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+
+	return result
+}
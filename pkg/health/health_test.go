@@ -0,0 +1,216 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func serve(t *testing.T, checker *Checker, url string) (int, Report) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	checker.Register(router)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+
+	var report Report
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("bad report %s: %v", w.Body, err)
+	}
+	return w.Code, report
+}
+
+func passing(ctx context.Context) error {
+	return nil
+}
+
+func failing(ctx context.Context) error {
+	return errors.New("connection refused")
+}
+
+// hanging waits for the timeout of the check.
+func hanging(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestReadyz(t *testing.T) {
+	tests := []struct {
+		name       string
+		checks     []Check
+		wantStatus int
+		want       Report
+	}{
+		{
+			name:       "no checks",
+			wantStatus: http.StatusOK,
+			want:       Report{Status: StatusOK},
+		},
+		{
+			name:       "passing",
+			checks:     []Check{{Name: "database", Func: passing}, {Name: "migrations", Func: passing}},
+			wantStatus: http.StatusOK,
+			want: Report{Status: StatusOK, Checks: map[string]CheckResult{
+				"database":   {Status: StatusOK},
+				"migrations": {Status: StatusOK},
+			}},
+		},
+		{
+			name:       "required failing",
+			checks:     []Check{{Name: "database", Func: failing}, {Name: "migrations", Func: passing}},
+			wantStatus: http.StatusServiceUnavailable,
+			want: Report{Status: StatusFail, Checks: map[string]CheckResult{
+				"database":   {Status: StatusFail, Error: "connection refused"},
+				"migrations": {Status: StatusOK},
+			}},
+		},
+		{
+			name:       "optional failing",
+			checks:     []Check{{Name: "database", Func: passing}, {Name: "enrichment_agify", Optional: true, Func: failing}},
+			wantStatus: http.StatusOK,
+			want: Report{Status: StatusOK, Checks: map[string]CheckResult{
+				"database":         {Status: StatusOK},
+				"enrichment_agify": {Status: StatusFail, Error: "connection refused", Optional: true},
+			}},
+		},
+		{
+			name:       "optional passing",
+			checks:     []Check{{Name: "enrichment_agify", Optional: true, Func: passing}},
+			wantStatus: http.StatusOK,
+			want: Report{Status: StatusOK, Checks: map[string]CheckResult{
+				"enrichment_agify": {Status: StatusOK, Optional: true},
+			}},
+		},
+		{
+			name:       "optional and required failing",
+			checks:     []Check{{Name: "database", Func: failing}, {Name: "enrichment_agify", Optional: true, Func: failing}},
+			wantStatus: http.StatusServiceUnavailable,
+			want: Report{Status: StatusFail, Checks: map[string]CheckResult{
+				"database":         {Status: StatusFail, Error: "connection refused"},
+				"enrichment_agify": {Status: StatusFail, Error: "connection refused", Optional: true},
+			}},
+		},
+		{
+			name:       "timed out",
+			checks:     []Check{{Name: "database", Timeout: 10 * time.Millisecond, Func: hanging}},
+			wantStatus: http.StatusServiceUnavailable,
+			want: Report{Status: StatusFail, Checks: map[string]CheckResult{
+				"database": {Status: StatusFail, Error: context.DeadlineExceeded.Error()},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, got := serve(t, NewChecker(zap.NewNop(), tt.checks...), ReadinessURL)
+			if code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", code, tt.wantStatus)
+			}
+			if got.Status != tt.want.Status || len(got.Checks) != len(tt.want.Checks) {
+				t.Fatalf("report = %+v, want %+v", got, tt.want)
+			}
+			for name, want := range tt.want.Checks {
+				result := got.Checks[name]
+				result.DurationMs = 0
+				if result != want {
+					t.Errorf("check %s = %+v, want %+v", name, result, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDrain(t *testing.T) {
+	var (
+		mu  sync.Mutex
+		ran int
+	)
+	checker := NewChecker(zap.NewNop(), Check{Name: "database", Func: func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+		ran++
+		return nil
+	}})
+
+	if code, _ := serve(t, checker, ReadinessURL); code != http.StatusOK {
+		t.Fatalf("status before the drain = %d, want %d", code, http.StatusOK)
+	}
+
+	checker.Drain()
+
+	// The draining service is unready without running the checks, but it is alive
+	code, report := serve(t, checker, ReadinessURL)
+	if code != http.StatusServiceUnavailable || report.Status != StatusDraining || report.Checks != nil {
+		t.Errorf("readiness while draining = %d %+v, want %d %s", code, report, http.StatusServiceUnavailable, StatusDraining)
+	}
+	if ran != 1 {
+		t.Errorf("checks ran %d times, want once before the drain", ran)
+	}
+	if code, report := serve(t, checker, LivenessURL); code != http.StatusOK || report.Status != StatusOK {
+		t.Errorf("liveness while draining = %d %+v, want %d %s", code, report, http.StatusOK, StatusOK)
+	}
+}
+
+func TestLivezIgnoresChecks(t *testing.T) {
+	checker := NewChecker(zap.NewNop(), Check{Name: "database", Func: failing})
+	if code, report := serve(t, checker, LivenessURL); code != http.StatusOK || report.Status != StatusOK || report.Checks != nil {
+		t.Errorf("liveness = %d %+v, want %d %s", code, report, http.StatusOK, StatusOK)
+	}
+}
+
+func TestRunConcurrently(t *testing.T) {
+	// Each check waits for the other one to start, so they time out if run one by one
+	var started sync.WaitGroup
+	started.Add(2)
+	waitForOther := func(ctx context.Context) error {
+		started.Done()
+		done := make(chan struct{})
+		go func() {
+			started.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	checker := NewChecker(zap.NewNop(),
+		Check{Name: "database", Timeout: time.Second, Func: waitForOther},
+		Check{Name: "migrations", Timeout: time.Second, Func: waitForOther},
+	)
+	if report := checker.Run(context.Background()); report.Status != StatusOK {
+		t.Errorf("report = %+v, want the checks to pass", report)
+	}
+}
+
+func TestDefaultTimeout(t *testing.T) {
+	var deadline time.Duration
+	checker := NewChecker(zap.NewNop(), Check{Name: "database", Func: func(ctx context.Context) error {
+		d, ok := ctx.Deadline()
+		if !ok {
+			return errors.New("no deadline")
+		}
+		deadline = time.Until(d)
+		return nil
+	}})
+
+	if report := checker.Run(context.Background()); report.Status != StatusOK {
+		t.Fatalf("report = %+v", report)
+	}
+	if deadline <= defaultTimeout-time.Second || deadline > defaultTimeout {
+		t.Errorf("deadline in %v, want %v", deadline, defaultTimeout)
+	}
+}
@@ -0,0 +1,31 @@
+package logger
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+const (
+	ContextKey = "logger"
+)
+
+// WithContext stores the request-scoped logger in the context.
+func WithContext(ctx context.Context, l *zap.Logger) context.Context {
+	return context.WithValue(ctx, ContextKey, l)
+}
+
+// FromContext returns the request-scoped logger of the context. If there is
+// none, it returns the fallback, or the global logger if the fallback is nil.
+func FromContext(ctx context.Context, fallback *zap.Logger) *zap.Logger {
+	if l, ok := ctx.Value(ContextKey).(*zap.Logger); ok {
+		return l
+	}
+	if fallback != nil {
+		return fallback
+	}
+	if logger != nil {
+		return logger
+	}
+	return zap.NewNop()
+}
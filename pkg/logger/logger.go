@@ -1,32 +1,159 @@
 package logger
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
+	"sync"
+	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
-var logger *zap.Logger
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
+
+var (
+	logger *zap.Logger
+	level  = zap.NewAtomicLevel()
+)
+
+// Config of the logger. Zero values keep the defaults: info level and console format.
+type Config struct {
+	// Level is debug, info, warn or error. It can be changed at runtime through Level().
+	Level string
+	// Format of the stdout output, json or console. The file is always written in json.
+	Format string
+	File   FileConfig
+}
+
+// FileConfig of the json log file. The file is rotated when it reaches MaxSizeMB
+// or every RotateInterval, and the rotated files are kept for MaxAgeDays and up to MaxBackups.
+type FileConfig struct {
+	Enabled        bool
+	Path           string
+	MaxSizeMB      int
+	MaxAgeDays     int
+	MaxBackups     int
+	RotateInterval time.Duration
+	Compress       bool
+}
+
+// Init makes the logger. The returned close func must be called before the process
+// exits: it flushes the logger, stops the file rotation and closes the log file.
+func Init(cfg Config) (*zap.Logger, func() error, error) {
+	if cfg.Level != "" {
+		if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+			return nil, nil, fmt.Errorf("bad log level %q", cfg.Level)
+		}
+	}
 
-func Init() *zap.Logger {
 	config := zap.NewProductionEncoderConfig()
 	config.EncodeTime = zapcore.ISO8601TimeEncoder
-	fileEncoder := zapcore.NewJSONEncoder(config)
-	logFile, _ := os.OpenFile("log.json", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	writer := zapcore.AddSync(logFile)
-	consoleEncoder := zapcore.NewConsoleEncoder(config)
-	defaultLogLevel := zapcore.DebugLevel
-	core := zapcore.NewTee(
-		zapcore.NewCore(fileEncoder, writer, defaultLogLevel),
-		zapcore.NewCore(consoleEncoder, zapcore.AddSync(os.Stdout), defaultLogLevel),
-	)
-
-	l := zap.New(core, zap.AddCaller())
+
+	var consoleEncoder zapcore.Encoder
+	switch cfg.Format {
+	case "", FormatConsole:
+		consoleEncoder = zapcore.NewConsoleEncoder(config)
+	case FormatJSON:
+		consoleEncoder = zapcore.NewJSONEncoder(config)
+	default:
+		return nil, nil, fmt.Errorf("bad log format %q", cfg.Format)
+	}
+	cores := []zapcore.Core{
+		zapcore.NewCore(consoleEncoder, zapcore.AddSync(os.Stdout), level),
+	}
+
+	closeFile := func() error { return nil }
+	if cfg.File.Enabled {
+		writer, closeWriter, err := newFileWriter(cfg.File)
+		if err != nil {
+			return nil, nil, err
+		}
+		cores = append(cores, zapcore.NewCore(zapcore.NewJSONEncoder(config), writer, level))
+		closeFile = closeWriter
+	}
+
+	l := zap.New(zapcore.NewTee(cores...), zap.AddCaller())
 	logger = l
-	return l
+
+	closeLogger := func() error {
+		// Syncing stdout fails for terminals and pipes, so only the file errors are reported
+		l.Sync()
+		return closeFile()
+	}
+	return l, closeLogger, nil
+}
+
+// newFileWriter opens the log file, so a bad path is reported at once instead of on the first write.
+// The returned close func stops the rotation and closes the file.
+func newFileWriter(cfg FileConfig) (zapcore.WriteSyncer, func() error, error) {
+	if cfg.Path == "" {
+		return nil, nil, fmt.Errorf("log file path is required")
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0755); err != nil {
+		return nil, nil, fmt.Errorf("creating log directory: %w", err)
+	}
+	logFile, err := os.OpenFile(cfg.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("opening log file: %w", err)
+	}
+	logFile.Close()
+
+	rotator := &lumberjack.Logger{
+		Filename:   cfg.Path,
+		MaxSize:    cfg.MaxSizeMB,
+		MaxAge:     cfg.MaxAgeDays,
+		MaxBackups: cfg.MaxBackups,
+		LocalTime:  true,
+		Compress:   cfg.Compress,
+	}
+
+	stopRotation := make(chan struct{})
+	rotationDone := make(chan struct{})
+	go func() {
+		defer close(rotationDone)
+		if cfg.RotateInterval <= 0 {
+			return
+		}
+
+		ticker := time.NewTicker(cfg.RotateInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopRotation:
+				return
+			case <-ticker.C:
+				if err := rotator.Rotate(); err != nil {
+					fmt.Fprintf(os.Stderr, "Error rotating log file: %s\n", err)
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	closeWriter := func() error {
+		var err error
+		once.Do(func() {
+			close(stopRotation)
+			<-rotationDone
+			err = rotator.Close()
+		})
+		return err
+	}
+
+	return zapcore.AddSync(rotator), closeWriter, nil
 }
 
 func GetLogger() *zap.Logger {
 	return logger
 }
+
+// Level is the level of the loggers made by Init. It is safe to change concurrently.
+func Level() *zap.AtomicLevel {
+	return &level
+}
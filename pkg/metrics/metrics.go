@@ -0,0 +1,144 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+)
+
+const (
+	MetricsURL = "/metrics"
+
+	namespace = "task_junior"
+	// unmatchedRoute labels requests to unknown routes, so paths don't blow up the label values.
+	unmatchedRoute = "unmatched"
+)
+
+// Metric serves the heartbeat and the Prometheus metrics of the application.
+// The metrics are kept in its own registry with the Go runtime and process collectors.
+type Metric struct {
+	Logger   *zap.Logger
+	Registry *prometheus.Registry
+
+	httpRequests        *prometheus.CounterVec
+	httpRequestDuration *prometheus.HistogramVec
+	dbQueryDuration     *prometheus.HistogramVec
+	dbQueryErrors       *prometheus.CounterVec
+	enrichmentDuration  *prometheus.HistogramVec
+	enrichmentErrors    *prometheus.CounterVec
+	cacheRequests       *prometheus.CounterVec
+}
+
+func New(logger *zap.Logger) *Metric {
+	m := &Metric{
+		Logger:   logger,
+		Registry: prometheus.NewRegistry(),
+
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of HTTP requests by route and status.",
+		}, []string{"method", "route", "status"}),
+		httpRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of HTTP requests by route and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		dbQueryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "db_query_duration_seconds",
+			Help:      "Duration of database queries by operation and table.",
+			Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"operation", "table"}),
+		dbQueryErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "db_query_errors_total",
+			Help:      "Number of failed database queries by operation and table.",
+		}, []string{"operation", "table"}),
+		enrichmentDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "enrichment_request_duration_seconds",
+			Help:      "Latency of enrichment upstream requests by provider.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"provider"}),
+		enrichmentErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "enrichment_errors_total",
+			Help:      "Number of failed enrichment upstream requests by provider.",
+		}, []string{"provider"}),
+		cacheRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_requests_total",
+			Help:      "Number of cache lookups by cache and result, hit or miss.",
+		}, []string{"cache", "result"}),
+	}
+
+	m.Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpRequestDuration,
+		m.dbQueryDuration,
+		m.dbQueryErrors,
+		m.enrichmentDuration,
+		m.enrichmentErrors,
+		m.cacheRequests,
+	)
+
+	return m
+}
+
+func (m *Metric) Register(router *gin.Engine) {
+	router.GET(URL, m.Heartbeat)
+	router.GET(MetricsURL, gin.WrapH(promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{})))
+}
+
+// Middleware counts the requests and observes their latency by the route template.
+func (m *Metric) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		status := strconv.Itoa(c.Writer.Status())
+
+		m.httpRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+		m.httpRequestDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// ObserveDBQuery records the duration of the database query and whether it has failed.
+func (m *Metric) ObserveDBQuery(operation, table string, duration time.Duration, err error) {
+	m.dbQueryDuration.WithLabelValues(operation, table).Observe(duration.Seconds())
+	if err != nil {
+		m.dbQueryErrors.WithLabelValues(operation, table).Inc()
+	}
+}
+
+// ObserveEnrichment records the latency of the enrichment upstream request and whether it has failed.
+func (m *Metric) ObserveEnrichment(provider string, duration time.Duration, err error) {
+	m.enrichmentDuration.WithLabelValues(provider).Observe(duration.Seconds())
+	if err != nil {
+		m.enrichmentErrors.WithLabelValues(provider).Inc()
+	}
+}
+
+// ObserveCache records the cache lookup. The hit ratio is the rate of
+// hits divided by the rate of all lookups of the cache.
+func (m *Metric) ObserveCache(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.cacheRequests.WithLabelValues(cache, result).Inc()
+}
@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestObserveCache(t *testing.T) {
+	m := New(zap.NewNop())
+	m.ObserveCache("enrichment_agify", false)
+	m.ObserveCache("enrichment_agify", true)
+	m.ObserveCache("enrichment_agify", true)
+
+	families, err := m.Registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	got := make(map[string]float64)
+	for _, family := range families {
+		if family.GetName() != "task_junior_cache_requests_total" {
+			continue
+		}
+		for _, v := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range v.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			got[labels["cache"]+" "+labels["result"]] = v.GetCounter().GetValue()
+		}
+	}
+
+	want := map[string]float64{"enrichment_agify hit": 2, "enrichment_agify miss": 1}
+	if len(got) != len(want) {
+		t.Fatalf("cache requests = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("cache requests = %v, want %v", got, want)
+		}
+	}
+}
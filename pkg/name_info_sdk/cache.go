@@ -0,0 +1,118 @@
+package name_info_sdk
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// CacheObserver is called after every cache lookup with the provider and whether the answer was cached.
+type CacheObserver func(provider string, hit bool)
+
+// WithCache keeps up to size answers of the upstream APIs for ttl, so repeated
+// names don't use the quota. The least recently used answer is evicted first.
+// Zero size or ttl disables the cache.
+func WithCache(size int, ttl time.Duration) Option {
+	return func(n *NameInfo) {
+		if size <= 0 || ttl <= 0 {
+			n.cache = nil
+			return
+		}
+		n.cache = newCache(size, ttl)
+	}
+}
+
+func WithCacheObserver(observer CacheObserver) Option {
+	return func(n *NameInfo) {
+		n.cacheObserver = observer
+	}
+}
+
+type cacheKey struct {
+	reqInfo RequestInfo
+	name    string
+}
+
+type cacheEntry struct {
+	key     cacheKey
+	value   interface{}
+	expires time.Time
+}
+
+type cache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[cacheKey]*list.Element
+	// order has the most recently used entries at the front.
+	order *list.List
+	now   func() time.Time
+}
+
+func newCache(size int, ttl time.Duration) *cache {
+	return &cache{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[cacheKey]*list.Element),
+		order:   list.New(),
+		now:     time.Now,
+	}
+}
+
+func (c *cache) get(key cacheKey) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*cacheEntry)
+	if !c.now().Before(entry.expires) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	c.order.MoveToFront(element)
+	return entry.value, true
+}
+
+func (c *cache) set(key cacheKey, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := c.now().Add(c.ttl)
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*cacheEntry)
+		entry.value, entry.expires = value, expires
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, value: value, expires: expires})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// cached looks the answer up in the cache and reports the lookup to the observer.
+func (n *NameInfo) cached(reqInfo RequestInfo, name string) (interface{}, bool) {
+	if n.cache == nil {
+		return nil, false
+	}
+
+	value, ok := n.cache.get(cacheKey{reqInfo: reqInfo, name: name})
+	if n.cacheObserver != nil {
+		n.cacheObserver(reqInfo.Provider(), ok)
+	}
+	return value, ok
+}
+
+func (n *NameInfo) store(reqInfo RequestInfo, name string, value interface{}) {
+	if n.cache != nil {
+		n.cache.set(cacheKey{reqInfo: reqInfo, name: name}, value)
+	}
+}
@@ -0,0 +1,108 @@
+package name_info_sdk
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	now := time.Now()
+	c := newCache(2, time.Minute)
+	c.now = func() time.Time { return now }
+
+	ivan := cacheKey{reqInfo: Age, name: "Ivan"}
+	petr := cacheKey{reqInfo: Age, name: "Petr"}
+	sidor := cacheKey{reqInfo: Age, name: "Sidor"}
+
+	c.set(ivan, 30)
+	c.set(petr, 40)
+	if _, ok := c.get(cacheKey{reqInfo: Gender, name: "Ivan"}); ok {
+		t.Error("answer of agify is cached for genderize")
+	}
+	// Ivan is used, so Petr is evicted
+	if v, ok := c.get(ivan); !ok || v != 30 {
+		t.Errorf("get(Ivan) = %v, %v, want 30, true", v, ok)
+	}
+	c.set(sidor, 50)
+	if _, ok := c.get(petr); ok {
+		t.Error("least recently used Petr is not evicted")
+	}
+	if _, ok := c.get(ivan); !ok {
+		t.Error("Ivan is evicted")
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := c.get(sidor); ok {
+		t.Error("expired Sidor is returned")
+	}
+	if len(c.entries) != 1 || c.order.Len() != 1 {
+		t.Errorf("got %d entries and %d in order after the expiry, want 1", len(c.entries), c.order.Len())
+	}
+}
+
+func TestGetAgeInfoByNameCached(t *testing.T) {
+	requests := 0
+	status := http.StatusOK
+	type lookup struct {
+		provider string
+		hit      bool
+	}
+	var lookups []lookup
+
+	n := NewNameInfo("", WithCache(10, time.Hour), WithCacheObserver(func(provider string, hit bool) {
+		lookups = append(lookups, lookup{provider, hit})
+	})).(*NameInfo)
+	n.client = &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		requests++
+		return &http.Response{
+			StatusCode: status,
+			Body:       io.NopCloser(strings.NewReader(`{"age":30}`)),
+			Request:    r,
+		}, nil
+	})}
+
+	// The failed answer is not cached
+	status = http.StatusTooManyRequests
+	if _, err := n.GetAgeInfoByName(context.Background(), "Ivan"); err == nil {
+		t.Fatal("GetAgeInfoByName() of the failed request has no error")
+	}
+	status = http.StatusOK
+	for i := 0; i < 2; i++ {
+		info, err := n.GetAgeInfoByName(context.Background(), "Ivan")
+		if err != nil {
+			t.Fatalf("GetAgeInfoByName() error = %v", err)
+		}
+		if info.Age != 30 || info.Name != "Ivan" {
+			t.Errorf("GetAgeInfoByName() = %+v", info)
+		}
+		// The caller may change the answer, the cached one stays
+		info.Age = 0
+	}
+
+	if requests != 2 {
+		t.Errorf("got %d upstream requests, want 2", requests)
+	}
+	want := []lookup{{"agify", false}, {"agify", false}, {"agify", true}}
+	if len(lookups) != len(want) {
+		t.Fatalf("lookups = %v, want %v", lookups, want)
+	}
+	for i := range want {
+		if lookups[i] != want[i] {
+			t.Errorf("lookups = %v, want %v", lookups, want)
+			break
+		}
+	}
+}
+
+func TestWithCacheDisabled(t *testing.T) {
+	if n := NewNameInfo("", WithCache(0, time.Hour)).(*NameInfo); n.cache != nil {
+		t.Error("cache of zero size is enabled")
+	}
+	if n := NewNameInfo("", WithCache(10, 0)).(*NameInfo); n.cache != nil {
+		t.Error("cache with zero ttl is enabled")
+	}
+}
@@ -1,10 +1,17 @@
 package name_info_sdk
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
+	"time"
+
+	"github.com/maxik12233/task-junior/pkg/logger"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.uber.org/zap"
 )
 
 const (
@@ -21,25 +28,114 @@ const (
 	Nationality
 )
 
+// providers name the upstream APIs in observed requests.
+var providers = map[RequestInfo]string{
+	Age:         "agify",
+	Gender:      "genderize",
+	Nationality: "nationalize",
+}
+
+// Provider is the name of the upstream API, e.g. "agify".
+func (r RequestInfo) Provider() string {
+	return providers[r]
+}
+
 var (
 	errCodeNotOK = errors.New("Something went wrong on the foreign API side")
 )
 
 type INameInfo interface {
-	GetGenderInfoByName(name string) (*LikelyGender, error)
-	GetAgeInfoByName(name string) (*LikelyAge, error)
-	GetLikelyNationalityInfoByName(name string) (*LikelyNationality, error)
+	GetGenderInfoByName(ctx context.Context, name string) (*LikelyGender, error)
+	GetAgeInfoByName(ctx context.Context, name string) (*LikelyAge, error)
+	GetLikelyNationalityInfoByName(ctx context.Context, name string) (*LikelyNationality, error)
+	Probe(ctx context.Context, reqInfo RequestInfo) error
+}
+
+// RequestObserver is called after every upstream request with its provider,
+// duration and error, if the request has failed.
+type RequestObserver func(provider string, duration time.Duration, err error)
+
+type Option func(n *NameInfo)
+
+func WithRequestObserver(observer RequestObserver) Option {
+	return func(n *NameInfo) {
+		n.observer = observer
+	}
 }
 
 type NameInfo struct {
-	apiKey string
-	client *http.Client
+	apiKey        string
+	client        *http.Client
+	observer      RequestObserver
+	cache         *cache
+	cacheObserver CacheObserver
 }
 
-func NewNameInfo(apiKey string) INameInfo {
-	return &NameInfo{
+func NewNameInfo(apiKey string, options ...Option) INameInfo {
+	n := &NameInfo{
 		apiKey: apiKey,
-		client: http.DefaultClient,
+		// Requests are traced, and the trace context is propagated in the traceparent header.
+		// The query holds the api key and the name, so it is kept out of the spans.
+		client: &http.Client{
+			Transport: hideQuery(otelhttp.NewTransport(restoreQuery(http.DefaultTransport),
+				otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+					return "GET " + r.URL.Host
+				}),
+			)),
+		},
+	}
+	for _, option := range options {
+		option(n)
+	}
+	return n
+}
+
+type queryContextKey struct{}
+
+type roundTripperFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// hideQuery moves the query of the request into its context, so the next
+// transport doesn't see it. restoreQuery puts it back before the request is sent.
+func hideQuery(next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.RawQuery == "" {
+			return next.RoundTrip(r)
+		}
+
+		hidden := r.Clone(context.WithValue(r.Context(), queryContextKey{}, r.URL.RawQuery))
+		hidden.URL.RawQuery = ""
+		return next.RoundTrip(hidden)
+	})
+}
+
+func restoreQuery(next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		query, ok := r.Context().Value(queryContextKey{}).(string)
+		if !ok {
+			return next.RoundTrip(r)
+		}
+
+		restored := r.Clone(r.Context())
+		restored.URL.RawQuery = query
+		return next.RoundTrip(restored)
+	})
+}
+
+// observe logs the upstream request with the logger of the context and reports it to the observer.
+func (n *NameInfo) observe(ctx context.Context, reqInfo RequestInfo, start time.Time, err *error) {
+	duration := time.Since(start)
+	logger.FromContext(ctx, nil).Debug("Name info request",
+		zap.String("provider", reqInfo.Provider()),
+		zap.Duration("duration", duration),
+		zap.Error(*err),
+	)
+
+	if n.observer != nil {
+		n.observer(reqInfo.Provider(), duration, *err)
 	}
 }
 
@@ -65,9 +161,35 @@ func (n *NameInfo) buildURL(reqInfo RequestInfo, name string) url.URL {
 	return *parsedUrl
 }
 
-func (n *NameInfo) DoHttpRequest(url string) (*http.Response, error) {
+// Probe checks that the upstream API answers. The request has no name,
+// so it doesn't count against the quota.
+func (n *NameInfo) Probe(ctx context.Context, reqInfo RequestInfo) error {
+	url := n.buildURL(reqInfo, "")
+	q := url.Query()
+	q.Del("name")
+	url.RawQuery = q.Encode()
 
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("%s answered with status %d", reqInfo.Provider(), resp.StatusCode)
+	}
+	return nil
+}
+
+// DoHttpRequest sends the GET request. The body of the returned response must be closed.
+func (n *NameInfo) DoHttpRequest(ctx context.Context, url string) (*http.Response, error) {
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -78,20 +200,32 @@ func (n *NameInfo) DoHttpRequest(url string) (*http.Response, error) {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return resp, errCodeNotOK
+		resp.Body.Close()
+		return nil, errCodeNotOK
 	}
 
 	return resp, nil
 }
 
-func (n *NameInfo) GetGenderInfoByName(name string) (*LikelyGender, error) {
+func (n *NameInfo) GetGenderInfoByName(ctx context.Context, name string) (info *LikelyGender, err error) {
+	if cached, ok := n.cached(Gender, name); ok {
+		info := cached.(LikelyGender)
+		return &info, nil
+	}
+	defer func() {
+		if err == nil {
+			n.store(Gender, name, *info)
+		}
+	}()
+	defer n.observe(ctx, Gender, time.Now(), &err)
 
 	url := n.buildURL(Gender, name)
 
-	resp, err := n.DoHttpRequest(url.String())
+	resp, err := n.DoHttpRequest(ctx, url.String())
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 
 	var genderInfo GenderResponse
 	if err := json.NewDecoder(resp.Body).Decode(&genderInfo); err != nil {
@@ -104,14 +238,25 @@ func (n *NameInfo) GetGenderInfoByName(name string) (*LikelyGender, error) {
 	}, nil
 }
 
-func (n *NameInfo) GetAgeInfoByName(name string) (*LikelyAge, error) {
+func (n *NameInfo) GetAgeInfoByName(ctx context.Context, name string) (info *LikelyAge, err error) {
+	if cached, ok := n.cached(Age, name); ok {
+		info := cached.(LikelyAge)
+		return &info, nil
+	}
+	defer func() {
+		if err == nil {
+			n.store(Age, name, *info)
+		}
+	}()
+	defer n.observe(ctx, Age, time.Now(), &err)
 
 	url := n.buildURL(Age, name)
 
-	resp, err := n.DoHttpRequest(url.String())
+	resp, err := n.DoHttpRequest(ctx, url.String())
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 
 	var ageInfo AgeResponse
 	if err := json.NewDecoder(resp.Body).Decode(&ageInfo); err != nil {
@@ -124,14 +269,25 @@ func (n *NameInfo) GetAgeInfoByName(name string) (*LikelyAge, error) {
 	}, nil
 }
 
-func (n *NameInfo) GetLikelyNationalityInfoByName(name string) (*LikelyNationality, error) {
+func (n *NameInfo) GetLikelyNationalityInfoByName(ctx context.Context, name string) (info *LikelyNationality, err error) {
+	if cached, ok := n.cached(Nationality, name); ok {
+		info := cached.(LikelyNationality)
+		return &info, nil
+	}
+	defer func() {
+		if err == nil {
+			n.store(Nationality, name, *info)
+		}
+	}()
+	defer n.observe(ctx, Nationality, time.Now(), &err)
 
 	url := n.buildURL(Nationality, name)
 
-	resp, err := n.DoHttpRequest(url.String())
+	resp, err := n.DoHttpRequest(ctx, url.String())
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 
 	var nationalityInfo NationalityResponse
 	if err := json.NewDecoder(resp.Body).Decode(&nationalityInfo); err != nil {
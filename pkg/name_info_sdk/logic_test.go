@@ -0,0 +1,91 @@
+package name_info_sdk
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maxik12233/task-junior/pkg/logger"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestQueryIsNotTraced(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(previous)
+
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.URL.RawQuery
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	n := NewNameInfo("secret").(*NameInfo)
+	resp, err := n.DoHttpRequest(context.Background(), server.URL+"?apikey=secret&name=Ivan")
+	if err != nil {
+		t.Fatalf("DoHttpRequest() error = %v", err)
+	}
+	resp.Body.Close()
+
+	if received != "apikey=secret&name=Ivan" {
+		t.Errorf("upstream got query %q", received)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	for _, v := range spans[0].Attributes() {
+		if value := v.Value.Emit(); strings.Contains(value, "secret") || strings.Contains(value, "Ivan") {
+			t.Errorf("span attribute %s = %q has the query", v.Key, value)
+		}
+	}
+}
+
+func TestDoHttpRequestNotOK(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	n := NewNameInfo("").(*NameInfo)
+	resp, err := n.DoHttpRequest(context.Background(), server.URL)
+	if err != errCodeNotOK {
+		t.Errorf("DoHttpRequest() error = %v, want %v", err, errCodeNotOK)
+	}
+	if resp != nil {
+		t.Error("DoHttpRequest() returned the response of the failed request")
+	}
+}
+
+func TestRequestLogUsesContextLogger(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	ctx := logger.WithContext(context.Background(), zap.New(core).With(zap.String("request_id", "req-1")))
+
+	n := NewNameInfo("").(*NameInfo)
+	n.client = &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"gender":"male"}`)), Request: r}, nil
+	})}
+	if _, err := n.GetGenderInfoByName(ctx, "Ivan"); err != nil {
+		t.Fatalf("GetGenderInfoByName() error = %v", err)
+	}
+
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("got %d log entries, want 1", len(entries))
+	}
+	if fields := entries[0].ContextMap(); fields["request_id"] != "req-1" || fields["provider"] != "genderize" {
+		t.Errorf("log fields = %v, want the request id and the provider", fields)
+	}
+}
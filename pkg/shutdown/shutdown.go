@@ -0,0 +1,56 @@
+package shutdown
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Server is the http.Server being stopped.
+type Server interface {
+	Shutdown(ctx context.Context) error
+}
+
+// Step is a resource closed after the server, like the workers or the database pool.
+type Step struct {
+	Name string
+	Func func(ctx context.Context) error
+}
+
+type Config struct {
+	// Drain makes the service unready, nil skips the drain.
+	Drain func()
+	// DrainDelay is the time the service stays unready, but still serves requests,
+	// so the load balancers stop sending new ones.
+	DrainDelay time.Duration
+	// Timeout is the time to finish the requests in progress, zero waits for them all.
+	Timeout time.Duration
+	// Steps run one by one in their order after the server is stopped.
+	Steps []Step
+}
+
+// Run stops the service: drains it, lets the requests in progress finish, then runs the steps.
+// The steps run even if the server has not stopped in time.
+func Run(log *zap.Logger, server Server, cfg Config) {
+	if cfg.Drain != nil {
+		cfg.Drain()
+		time.Sleep(cfg.DrainDelay)
+	}
+
+	ctx := context.Background()
+	if cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+		defer cancel()
+	}
+	if err := server.Shutdown(ctx); err != nil {
+		log.Error("Error shutting down server, requests in progress are dropped", zap.Error(err))
+	}
+
+	for _, step := range cfg.Steps {
+		if err := step.Func(context.Background()); err != nil {
+			log.Error("Error shutting down "+step.Name, zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// recorder keeps the order of the shutdown events.
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) add(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.events...)
+}
+
+// startServer serves a request which is in progress until release is closed.
+func startServer(t *testing.T, rec *recorder, release <-chan struct{}) (*http.Server, <-chan error) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	started := make(chan struct{})
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		rec.add("request finished")
+		w.WriteHeader(http.StatusCreated)
+	})}
+	go server.Serve(listener)
+
+	// The error of the request in progress, nil if it has got the response
+	requestErr := make(chan error, 1)
+	go func() {
+		resp, err := http.Post("http://"+listener.Addr().String()+"/person", "application/json", nil)
+		if err == nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusCreated {
+				err = errors.New(resp.Status)
+			}
+		}
+		requestErr <- err
+	}()
+	<-started
+	return server, requestErr
+}
+
+func steps(rec *recorder) []Step {
+	return []Step{
+		{Name: "workers", Func: func(ctx context.Context) error {
+			rec.add("workers")
+			return nil
+		}},
+		{Name: "tracing", Func: func(ctx context.Context) error {
+			rec.add("tracing")
+			return errors.New("exporter is unavailable")
+		}},
+		{Name: "database connection", Func: func(ctx context.Context) error {
+			rec.add("database connection")
+			return nil
+		}},
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRun(t *testing.T) {
+	rec := &recorder{}
+	release := make(chan struct{})
+	server, requestErr := startServer(t, rec, release)
+
+	core, logs := observer.New(zapcore.ErrorLevel)
+	const drainDelay = 50 * time.Millisecond
+	var drainedAt time.Time
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Run(zap.New(core), server, Config{
+			Drain: func() {
+				drainedAt = time.Now()
+				rec.add("drain")
+				// The request in progress finishes after the drain and before the steps
+				go func() {
+					time.Sleep(2 * drainDelay)
+					close(release)
+				}()
+			},
+			DrainDelay: drainDelay,
+			Timeout:    time.Second,
+			Steps:      steps(rec),
+		})
+		rec.add("stopped")
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("shutdown has not finished")
+	}
+	if err := <-requestErr; err != nil {
+		t.Errorf("request in progress error = %v, want it to finish", err)
+	}
+	want := []string{"drain", "request finished", "workers", "tracing", "database connection", "stopped"}
+	if got := rec.get(); !equal(got, want) {
+		t.Errorf("order = %v, want %v", got, want)
+	}
+	if elapsed := time.Since(drainedAt); elapsed < drainDelay {
+		t.Errorf("stopped %v after the drain, want the drain delay %v", elapsed, drainDelay)
+	}
+
+	// The failed step is logged and the next ones still run
+	if entries := logs.FilterMessage("Error shutting down tracing").All(); len(entries) != 1 {
+		t.Errorf("got %d tracing errors logged, want 1", len(entries))
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	rec := &recorder{}
+	release := make(chan struct{})
+	defer close(release)
+	server, _ := startServer(t, rec, release)
+
+	core, logs := observer.New(zapcore.ErrorLevel)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// The server which has failed to start is not drained
+		Run(zap.New(core), server, Config{Timeout: 20 * time.Millisecond, Steps: steps(rec)})
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("shutdown is waiting for the request in progress after the timeout")
+	}
+	// The steps run even if the request in progress is dropped
+	want := []string{"workers", "tracing", "database connection"}
+	if got := rec.get(); !equal(got, want) {
+		t.Errorf("order = %v, want %v", got, want)
+	}
+	if entries := logs.FilterMessage("Error shutting down server, requests in progress are dropped").All(); len(entries) != 1 {
+		t.Errorf("got %d server errors logged, want 1", len(entries))
+	}
+}
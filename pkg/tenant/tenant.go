@@ -0,0 +1,90 @@
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	app "github.com/maxik12233/task-junior"
+	"github.com/maxik12233/task-junior/pkg/api"
+	"github.com/maxik12233/task-junior/pkg/api/auth"
+)
+
+const (
+	Header     = "X-Tenant-ID"
+	ContextKey = "tenant_id"
+	// Default is the tenant of requests which don't specify one.
+	Default = "default"
+)
+
+var idPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Middleware puts the tenant of the request into its context. The tenant bound
+// to the credentials takes precedence, then the X-Tenant-ID header, then Default.
+// A header naming another tenant than the credentials is rejected. If authenticated,
+// credentials not bound to a tenant need auth.PermissionTenantSwitch to use the header.
+// Tenant ids are case insensitive and lowercased. The tenant of the credentials,
+// e.g. the tenant_id claim of the token, is validated as the header.
+func Middleware(authenticated bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		id := c.GetHeader(Header)
+		if id != "" && !Valid(id) {
+			api.AbortWithError(c, app.WrapE(app.ErrBadRequest, "bad tenant id"))
+			return
+		}
+		id = Normalize(id)
+
+		bound := auth.Tenant(ctx)
+		if bound != "" && !Valid(bound) {
+			api.AbortWithError(c, app.WrapE(app.ErrUnauthorized, "credentials have a bad tenant id"))
+			return
+		}
+		bound = Normalize(bound)
+
+		if bound != "" {
+			if id != "" && id != bound {
+				api.AbortWithError(c, app.WrapE(app.ErrForbidden, fmt.Sprintf("credentials are bound to another tenant than %q", id)))
+				return
+			}
+			id = bound
+		} else if authenticated && id != "" && !auth.HasPermission(ctx, auth.PermissionTenantSwitch) {
+			api.AbortWithError(c, app.WrapE(app.ErrForbidden, fmt.Sprintf("permission %q is required to choose the tenant", auth.PermissionTenantSwitch)))
+			return
+		}
+
+		c.Request = c.Request.WithContext(WithID(c.Request.Context(), id))
+
+		c.Next()
+	}
+}
+
+// Valid reports whether id is a tenant id: up to 64 letters, digits, '_' and '-'.
+func Valid(id string) bool {
+	return idPattern.MatchString(id)
+}
+
+// Normalize returns the tenant id in the stored form. Config keys are lowercased
+// by the config loader, so ids are compared in lower case everywhere.
+func Normalize(id string) string {
+	return strings.ToLower(id)
+}
+
+// WithID returns the context with the normalized tenant id, Default if id is empty.
+func WithID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		id = Default
+	}
+	return context.WithValue(ctx, ContextKey, Normalize(id))
+}
+
+// ID returns the tenant of the context, Default if there is none.
+func ID(ctx context.Context) string {
+	if id, ok := ctx.Value(ContextKey).(string); ok {
+		return id
+	}
+	return Default
+}
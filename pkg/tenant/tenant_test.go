@@ -0,0 +1,68 @@
+package tenant
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/maxik12233/task-junior/pkg/api/auth"
+)
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name          string
+		authenticated bool
+		bound         string
+		scopes        []string
+		header        string
+		wantStatus    int
+		wantTenant    string
+	}{
+		{name: "default", wantStatus: http.StatusOK, wantTenant: Default},
+		{name: "header without authentication", header: "Team-A", wantStatus: http.StatusOK, wantTenant: "team-a"},
+		{name: "bad header", header: "team a", wantStatus: http.StatusBadRequest},
+		{name: "bound tenant", authenticated: true, bound: "Team-A", wantStatus: http.StatusOK, wantTenant: "team-a"},
+		{name: "bound tenant and same header", authenticated: true, bound: "team-a", header: "TEAM-A", wantStatus: http.StatusOK, wantTenant: "team-a"},
+		{name: "bound tenant and other header", authenticated: true, bound: "team-a", header: "team-b", wantStatus: http.StatusForbidden},
+		{name: "bound tenant with bad characters", authenticated: true, bound: "team/a", wantStatus: http.StatusUnauthorized},
+		{name: "bound tenant over 64 characters", authenticated: true, bound: strings.Repeat("a", 65), wantStatus: http.StatusUnauthorized},
+		{name: "unbound credentials without header", authenticated: true, wantStatus: http.StatusOK, wantTenant: Default},
+		{name: "unbound credentials choosing tenant", authenticated: true, scopes: []string{"admin"}, header: "team-a", wantStatus: http.StatusForbidden},
+		{name: "unbound credentials switching tenant", authenticated: true, scopes: []string{"tenant:switch"}, header: "team-a", wantStatus: http.StatusOK, wantTenant: "team-a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				ctx := context.WithValue(c.Request.Context(), auth.TenantContextKey, tt.bound)
+				ctx = context.WithValue(ctx, auth.ScopesContextKey, tt.scopes)
+				c.Request = c.Request.WithContext(ctx)
+			})
+			router.GET("/", Middleware(tt.authenticated), func(c *gin.Context) {
+				got = ID(c.Request.Context())
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(Header, tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if got != tt.wantTenant {
+				t.Errorf("tenant = %q, want %q", got, tt.wantTenant)
+			}
+		})
+	}
+}
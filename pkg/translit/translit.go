@@ -0,0 +1,43 @@
+package translit
+
+import (
+	"strings"
+	"unicode"
+)
+
+// icao is the ICAO Doc 9303 transliteration table for Cyrillic letters, which
+// is also used by GOST R 52535.1-2006 for Russian passports. GOST 7.79-2000
+// is not supported, as its marks like "`" and "'" don't suit the enrichment apis.
+// Migration 10_transliterated_names repeats the table in SQL.
+var icao = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "i", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "ie", 'ы': "y", 'ь': "", 'э': "e", 'ю': "iu", 'я': "ia",
+	// Ukrainian and Belarusian letters
+	'є': "ie", 'і': "i", 'ї': "i", 'ґ': "g", 'ў': "u",
+}
+
+// ICAO transliterates Cyrillic letters of s to Latin by ICAO Doc 9303.
+// Other characters are kept as is. Capitalization of the letters is preserved.
+func ICAO(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+
+	for _, r := range s {
+		latin, ok := icao[unicode.ToLower(r)]
+		if !ok {
+			b.WriteRune(r)
+			continue
+		}
+
+		if unicode.IsUpper(r) && latin != "" {
+			b.WriteString(strings.ToUpper(latin[:1]) + latin[1:])
+			continue
+		}
+		b.WriteString(latin)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,31 @@
+package translit
+
+import "testing"
+
+func TestICAO(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "", want: ""},
+		{in: "Иван", want: "Ivan"},
+		{in: "Щукин", want: "Shchukin"},
+		{in: "Юлия", want: "Iuliia"},
+		{in: "Ёлкина", want: "Elkina"},
+		{in: "Хабибуллин", want: "Khabibullin"},
+		{in: "Цой", want: "Tsoi"},
+		{in: "Подъячев", want: "Podieiachev"},
+		{in: "Игорь", want: "Igor"},
+		{in: "Мария-Анна", want: "Mariia-Anna"},
+		{in: "Їжак Ґалаган", want: "Izhak Galagan"},
+		{in: "Ўладзімір", want: "Uladzimir"},
+		{in: "John Smith", want: "John Smith"},
+		{in: "Анна Smith", want: "Anna Smith"},
+	}
+
+	for _, tt := range tests {
+		if got := ICAO(tt.in); got != tt.want {
+			t.Errorf("ICAO(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}